@@ -0,0 +1,118 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+)
+
+// ErrorCode classifies the errors returned by the JetStream API
+type ErrorCode string
+
+func (c ErrorCode) String() string { return strings.Title(strings.ReplaceAll(string(c), "_", " ")) }
+
+const (
+	ErrCodeUnknown               ErrorCode = "unknown"
+	ErrCodeStreamNotFound        ErrorCode = "stream_not_found"
+	ErrCodeConsumerNotFound      ErrorCode = "consumer_not_found"
+	ErrCodeTemplateNotFound      ErrorCode = "template_not_found"
+	ErrCodeMessageNotFound       ErrorCode = "message_not_found"
+	ErrCodeAlreadyExists         ErrorCode = "already_exists"
+	ErrCodeInsufficientResources ErrorCode = "insufficient_resources"
+	ErrCodeInvalidConfiguration  ErrorCode = "invalid_configuration"
+	ErrCodePermissionDenied      ErrorCode = "permission_denied"
+	ErrCodeNotEnabled            ErrorCode = "not_enabled"
+	ErrCodeBadRequest            ErrorCode = "bad_request"
+)
+
+// Sentinel errors that can be compared against errors returned by the JetStream API using errors.Is
+var (
+	ErrStreamNotFound        = &APIError{Code: ErrCodeStreamNotFound, Description: "stream not found"}
+	ErrConsumerNotFound      = &APIError{Code: ErrCodeConsumerNotFound, Description: "consumer not found"}
+	ErrTemplateNotFound      = &APIError{Code: ErrCodeTemplateNotFound, Description: "template not found"}
+	ErrMessageNotFound       = &APIError{Code: ErrCodeMessageNotFound, Description: "message not found"}
+	ErrAlreadyExists         = &APIError{Code: ErrCodeAlreadyExists, Description: "already exists"}
+	ErrInsufficientResources = &APIError{Code: ErrCodeInsufficientResources, Description: "insufficient resources"}
+	ErrInvalidConfiguration  = &APIError{Code: ErrCodeInvalidConfiguration, Description: "invalid configuration"}
+	ErrPermissionDenied      = &APIError{Code: ErrCodePermissionDenied, Description: "permission denied"}
+	ErrJetStreamNotEnabled   = &APIError{Code: ErrCodeNotEnabled, Description: "jetstream not enabled"}
+	ErrBadRequest            = &APIError{Code: ErrCodeBadRequest, Description: "bad request"}
+)
+
+// APIError is an error returned by the JetStream API, the Code classifies the error while Description holds the server supplied reason
+type APIError struct {
+	Code        ErrorCode
+	Description string
+}
+
+// the order matters, the first match wins so more specific patterns should be listed first
+var errorClassifications = []struct {
+	code     ErrorCode
+	patterns []string
+}{
+	{ErrCodeStreamNotFound, []string{"stream not found"}},
+	{ErrCodeConsumerNotFound, []string{"consumer not found"}},
+	{ErrCodeTemplateNotFound, []string{"no template", "template not found"}},
+	{ErrCodeMessageNotFound, []string{"no message found", "could not load message", "deleted msg", "] not found"}},
+	{ErrCodeAlreadyExists, []string{"already in use", "already exists"}},
+	{ErrCodeNotEnabled, []string{"not enabled"}},
+	{ErrCodeInsufficientResources, []string{"insufficient", "limits exceeded", "limit reached", "exceeds account limit", "maximum number of streams"}},
+	{ErrCodePermissionDenied, []string{"permission", "authorization"}},
+	{ErrCodeBadRequest, []string{"bad request", "bad sequence"}},
+	{ErrCodeInvalidConfiguration, []string{"configuration", "config", "invalid", "can not", "requires", "not allowed", "overlap", "duplicate", "maximum replicas", "not a valid"}},
+}
+
+// NewAPIError creates an APIError from the description the server sent, the description is used to classify the error
+func NewAPIError(description string) *APIError {
+	lower := strings.ToLower(description)
+
+	for _, c := range errorClassifications {
+		for _, p := range c.patterns {
+			if strings.Contains(lower, p) {
+				return &APIError{Code: c.code, Description: description}
+			}
+		}
+	}
+
+	return &APIError{Code: ErrCodeUnknown, Description: description}
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return e.Description
+}
+
+// Is supports errors.Is(), errors are considered equal when they have the same Code, unknown errors have to also have the same Description
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok {
+		return false
+	}
+
+	if e.Code == ErrCodeUnknown || t.Code == ErrCodeUnknown {
+		return e.Code == t.Code && e.Description == t.Description
+	}
+
+	return e.Code == t.Code
+}
+
+// NotFound determines if the error indicates that a stream, consumer, template or message does not exist
+func (e *APIError) NotFound() bool {
+	switch e.Code {
+	case ErrCodeStreamNotFound, ErrCodeConsumerNotFound, ErrCodeTemplateNotFound, ErrCodeMessageNotFound:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	cases := map[string]ErrorCode{
+		"stream not found":                                        ErrCodeStreamNotFound,
+		"consumer not found":                                      ErrCodeConsumerNotFound,
+		"no template found":                                       ErrCodeTemplateNotFound,
+		"could not load message from storage":                     ErrCodeMessageNotFound,
+		"sequence [10] not found":                                 ErrCodeMessageNotFound,
+		"stream name already in use":                              ErrCodeAlreadyExists,
+		"consumer already exists":                                 ErrCodeAlreadyExists,
+		"insufficient storage resources available":                ErrCodeInsufficientResources,
+		"resource limits exceeded for account":                    ErrCodeInsufficientResources,
+		"maximum number of streams reached":                       ErrCodeInsufficientResources,
+		"jetstream not enabled for account":                       ErrCodeNotEnabled,
+		"bad request":                                             ErrCodeBadRequest,
+		"stream configuration update can not change storage type": ErrCodeInvalidConfiguration,
+		"subjects overlap with an existing stream":                ErrCodeInvalidConfiguration,
+		"Permissions Violation for Publish":                       ErrCodePermissionDenied,
+		"something unexpected":                                    ErrCodeUnknown,
+	}
+
+	for desc, code := range cases {
+		err := NewAPIError(desc)
+		if err.Code != code {
+			t.Fatalf("expected %q to be %s got %s", desc, code, err.Code)
+		}
+
+		if err.Error() != desc {
+			t.Fatalf("expected description %q got %q", desc, err.Error())
+		}
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	err := fmt.Errorf("load failed: %w", NewAPIError("stream not found"))
+
+	if !errors.Is(err, ErrStreamNotFound) {
+		t.Fatalf("expected stream not found")
+	}
+
+	if errors.Is(err, ErrConsumerNotFound) {
+		t.Fatalf("did not expect consumer not found")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError")
+	}
+
+	if !apiErr.NotFound() {
+		t.Fatalf("expected a not found error")
+	}
+
+	if errors.Is(NewAPIError("one"), NewAPIError("two")) {
+		t.Fatalf("unknown errors with different descriptions should not match")
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 // Subjects used by the JetStream API
 const (
+	JetStreamAPIPrefix      = "$JS"
 	JetStreamEnabled        = "$JS.ENABLED"
 	JetStreamMetricPrefix   = "$JS.EVENT.METRIC"
 	JetStreamAdvisoryPrefix = "$JS.EVENT.ADVISORY"
@@ -39,6 +39,29 @@ type StoredMsg struct {
 	Time     time.Time `json:"time"`
 }
 
+// StreamPurgeRequest limits a purge to a subset of the Stream, servers that only support purging everything reject it
+type StreamPurgeRequest struct {
+	// Subject only purges messages matching this subject
+	Subject string `json:"filter,omitempty"`
+	// Sequence only purges messages with a lower sequence
+	Sequence uint64 `json:"seq,omitempty"`
+	// Keep is the number of most recent matching messages to keep
+	Keep uint64 `json:"keep,omitempty"`
+}
+
+// StreamMsgGetRequest loads the most recent message for a subject, servers that only load messages by sequence reject it
+type StreamMsgGetRequest struct {
+	LastBySubject string `json:"last_by_subj"`
+}
+
+// PubAck is the acknowledgement received after publishing a message into a Stream, e.g. +OK {"stream": "ORDERS", "seq": 22}
+type PubAck struct {
+	Stream   string `json:"stream"`
+	Sequence uint64 `json:"seq"`
+	// Duplicate indicates the message was not stored as a message with the same ID was seen within the duplicate window
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
 // StreamConfig is the configuration for a JetStream Stream Template
 //
 // NATS Schema Type io.nats.jetstream.api.v1.stream_configuration
@@ -0,0 +1,263 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+const archiveManifestFile = "manifest.json"
+
+// BackupManifest describes the contents of a configuration backup archive
+type BackupManifest struct {
+	Created time.Time `json:"created"`
+	// ServerID is the ID of the server the backup was made through
+	ServerID string `json:"server_id"`
+	// ServerVersion is the version of the server the backup was made through as given by BackupServerVersion, empty when not given
+	ServerVersion string `json:"server_version,omitempty"`
+	// Account is the JetStream usage and limits of the account at the time of the backup
+	Account         api.JetStreamAccountStats `json:"account"`
+	Streams         int                       `json:"streams"`
+	StreamTemplates int                       `json:"stream_templates"`
+	Consumers       int                       `json:"consumers"`
+	// Checksum is the sha256 checksum of all files in the archive, in archive order, excluding the manifest
+	Checksum string `json:"checksum"`
+}
+
+func isBackupArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+type archiveTarget struct {
+	file     string
+	f        *os.File
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	sum      hash.Hash
+	manifest *BackupManifest
+	bopts    *backupOptions
+}
+
+func newArchiveTarget(file string, bopts *backupOptions) (*archiveTarget, error) {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(f)
+
+	return &archiveTarget{
+		file:     file,
+		f:        f,
+		gz:       gz,
+		tw:       tar.NewWriter(gz),
+		sum:      sha256.New(),
+		manifest: &BackupManifest{Created: time.Now().UTC()},
+		bopts:    bopts,
+	}, nil
+}
+
+func (a *archiveTarget) path(name string) string {
+	return fmt.Sprintf("%s:%s", a.file, name)
+}
+
+func (a *archiveTarget) write(name string, btype string, data []byte) error {
+	err := a.writeFile(name, data)
+	if err != nil {
+		return err
+	}
+
+	a.sum.Write(data)
+
+	switch btype {
+	case "stream":
+		a.manifest.Streams++
+	case "stream_template":
+		a.manifest.StreamTemplates++
+	case "consumer":
+		a.manifest.Consumers++
+	}
+
+	return nil
+}
+
+func (a *archiveTarget) writeFile(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0640,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+
+	err := a.tw.WriteHeader(hdr)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.tw.Write(data)
+
+	return err
+}
+
+// finish writes the manifest and closes the archive
+func (a *archiveTarget) finish() (err error) {
+	defer func() {
+		if err != nil {
+			a.abort()
+		}
+	}()
+
+	ropts, err := newreqoptions(a.bopts.ropts...)
+	if err != nil {
+		return err
+	}
+
+	a.manifest.ServerID = ropts.nc.ConnectedServerId()
+	a.manifest.ServerVersion = a.bopts.version
+	a.manifest.Account, err = JetStreamAccountInfo(a.bopts.ropts...)
+	if err != nil {
+		return err
+	}
+
+	a.manifest.Checksum = fmt.Sprintf("%x", a.sum.Sum(nil))
+
+	mj, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = a.writeFile(archiveManifestFile, mj)
+	if err != nil {
+		return err
+	}
+
+	err = a.tw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = a.gz.Close()
+	if err != nil {
+		return err
+	}
+
+	return a.f.Close()
+}
+
+// abort removes the partially written archive
+func (a *archiveTarget) abort() {
+	a.f.Close()
+	os.Remove(a.file)
+}
+
+// LoadBackupManifest reads the manifest from a backup archive made by BackupJetStreamConfiguration and verifies the archive
+func LoadBackupManifest(file string) (*BackupManifest, error) {
+	_, manifest, err := loadBackupArchive(file, newBackupOptions())
+	return manifest, err
+}
+
+func loadBackupArchive(file string, bopts *backupOptions) ([]*BackupData, *BackupManifest, error) {
+	bopts.log.Debugf("Reading archive %s", file)
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+
+	var manifest *BackupManifest
+	backups := []*BackupData{}
+	sum := sha256.New()
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if hdr.Name == archiveManifestFile {
+			manifest = &BackupManifest{}
+			err = json.Unmarshal(b, manifest)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest in %s: %s", file, err)
+			}
+
+			continue
+		}
+
+		sum.Write(b)
+
+		bd := &BackupData{}
+		err = json.Unmarshal(b, bd)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid backup %s in %s: %s", hdr.Name, file, err)
+		}
+
+		if !verifySum(bd.Configuration, bd.Checksum) {
+			return nil, nil, fmt.Errorf("data checksum failed for %s in %s", hdr.Name, file)
+		}
+
+		backups = append(backups, bd)
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("%s does not contain a manifest", file)
+	}
+
+	if fmt.Sprintf("%x", sum.Sum(nil)) != manifest.Checksum {
+		return nil, nil, fmt.Errorf("archive checksum failed for %s", file)
+	}
+
+	counts := map[string]int{}
+	for _, b := range backups {
+		counts[b.Type]++
+	}
+
+	if counts["stream"] != manifest.Streams || counts["stream_template"] != manifest.StreamTemplates || counts["consumer"] != manifest.Consumers {
+		return nil, nil, fmt.Errorf("archive %s does not contain all the items listed in its manifest", file)
+	}
+
+	return backups, manifest, nil
+}
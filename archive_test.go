@@ -0,0 +1,139 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func TestBackupArchive(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.NewConsumer(jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	templ, err := jsm.NewStreamTemplate("ARCHIVE", 5, jsm.DefaultStream, jsm.MemoryStorage(), jsm.Subjects("ARCHIVE.*"))
+	checkErr(t, err, "template create failed")
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	archive := filepath.Join(td, "backup.tar.gz")
+	err = jsm.BackupJetStreamConfiguration(archive, jsm.BackupServerVersion(server.VERSION))
+	checkErr(t, err, "backup failed")
+
+	manifest, err := jsm.LoadBackupManifest(archive)
+	checkErr(t, err, "manifest failed")
+
+	if manifest.Streams != 1 || manifest.Consumers != 1 || manifest.StreamTemplates != 1 {
+		t.Fatalf("invalid counts in manifest: %+v", manifest)
+	}
+
+	if manifest.Account.Streams != 1 || manifest.ServerID != nc.ConnectedServerId() || manifest.ServerVersion != server.VERSION || manifest.Checksum == "" {
+		t.Fatalf("invalid manifest: %+v", manifest)
+	}
+
+	err = jsm.BackupJetStreamConfiguration(archive)
+	if err == nil {
+		t.Fatalf("expected existing archive to fail")
+	}
+
+	err = stream.Delete()
+	checkErr(t, err, "delete failed")
+	err = templ.Delete()
+	checkErr(t, err, "delete failed")
+
+	err = jsm.RestoreJetStreamConfiguration(archive, false)
+	checkErr(t, err, "restore failed")
+
+	known, err := jsm.IsKnownConsumer("ORDERS", "NEW")
+	checkErr(t, err, "known failed")
+	if !known {
+		t.Fatalf("NEW was not restored")
+	}
+
+	known, err = jsm.IsKnownStreamTemplate("ARCHIVE")
+	checkErr(t, err, "known failed")
+	if !known {
+		t.Fatalf("ARCHIVE was not restored")
+	}
+
+	// an archive missing a file does not verify
+	tampered := filepath.Join(td, "tampered.tgz")
+	copyArchiveWithout(t, archive, tampered, "stream_ORDERS_consumer_NEW.json")
+
+	_, err = jsm.LoadBackupManifest(tampered)
+	if err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected a checksum failure got %v", err)
+	}
+
+	err = jsm.RestoreJetStreamConfiguration(tampered, true)
+	if err == nil {
+		t.Fatalf("expected restore of a tampered archive to fail")
+	}
+}
+
+func copyArchiveWithout(t *testing.T, src string, dst string, skip string) {
+	t.Helper()
+
+	in, err := os.Open(src)
+	checkErr(t, err, "open failed")
+	defer in.Close()
+
+	gzr, err := gzip.NewReader(in)
+	checkErr(t, err, "gzip failed")
+
+	out, err := os.Create(dst)
+	checkErr(t, err, "create failed")
+	defer out.Close()
+
+	gzw := gzip.NewWriter(out)
+	tr := tar.NewReader(gzr)
+	tw := tar.NewWriter(gzw)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		checkErr(t, err, "read failed")
+
+		if hdr.Name == skip {
+			continue
+		}
+
+		checkErr(t, tw.WriteHeader(hdr), "write header failed")
+		_, err = io.Copy(tw, tr)
+		checkErr(t, err, "copy failed")
+	}
+
+	checkErr(t, tw.Close(), "close failed")
+	checkErr(t, gzw.Close(), "close failed")
+}
@@ -18,7 +18,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -40,46 +39,273 @@ type ConsumerBackup struct {
 	Config api.ConsumerConfig `json:"config"`
 }
 
-// BackupJetStreamConfiguration creates a backup of all configuration for Streams, Consumers and Stream Templates
-func BackupJetStreamConfiguration(backupDir string) error {
+// BackupOption configures backup and restore operations
+type BackupOption func(o *backupOptions)
+
+type backupOptions struct {
+	ropts     []RequestOption
+	log       Logger
+	progress  func(BackupEvent)
+	chunkSize int
+	version   string
+}
+
+// BackupPhase is the stage of backup or restore a single item is in
+type BackupPhase string
+
+const (
+	BackupPhaseStarted   BackupPhase = "started"
+	BackupPhaseCompleted BackupPhase = "completed"
+	BackupPhaseSkipped   BackupPhase = "skipped"
+	BackupPhaseFailed    BackupPhase = "failed"
+	BackupPhaseProgress  BackupPhase = "progress"
+)
+
+// BackupEvent reports progress of backup and restore operations
+type BackupEvent struct {
+	// Type is the kind of item being handled, one of stream, stream_template or consumer
+	Type string
+	// Stream is the Stream a consumer belongs to
+	Stream string
+	// Name is the name of the Stream, Stream Template or Consumer
+	Name  string
+	Phase BackupPhase
+	// Error is set when Phase is BackupPhaseFailed
+	Error error
+	// Sequence is the last Stream sequence handled by a stream data backup or restore
+	Sequence uint64
+	// Messages is the number of messages handled so far by a stream data backup or restore
+	Messages uint64
+}
+
+// BackupErrors is returned by backups that completed but failed to backup some items
+type BackupErrors []error
+
+// Error implements error
+func (e BackupErrors) Error() string {
+	errs := make([]string, len(e))
+	for i, err := range e {
+		errs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d errors during backup: %s", len(e), strings.Join(errs, ", "))
+}
+
+func newBackupOptions(opts ...BackupOption) *backupOptions {
+	bopts := &backupOptions{log: discardLogger{}, chunkSize: 10000}
+	for _, opt := range opts {
+		opt(bopts)
+	}
+
+	return bopts
+}
+
+func (o *backupOptions) emit(e BackupEvent) {
+	if o.progress == nil {
+		return
+	}
+
+	o.progress(e)
+}
+
+func (o *backupOptions) report(btype string, stream string, name string, phase BackupPhase, err error) {
+	o.emit(BackupEvent{Type: btype, Stream: stream, Name: name, Phase: phase, Error: err})
+}
+
+// reportResult reports the item as completed or, when err is set, failed
+func (o *backupOptions) reportResult(btype string, stream string, name string, err error) {
+	if err != nil {
+		o.report(btype, stream, name, BackupPhaseFailed, err)
+		return
+	}
+
+	o.report(btype, stream, name, BackupPhaseCompleted, nil)
+}
+
+// BackupLogger sets a logger to report on backup and restore progress, nothing is logged by default
+func BackupLogger(l Logger) BackupOption {
+	return func(o *backupOptions) {
+		if l == nil {
+			l = discardLogger{}
+		}
+
+		o.log = l
+	}
+}
+
+// BackupProgress sets a callback that is called as every Stream, Stream Template and Consumer is backed up or restored
+func BackupProgress(cb func(BackupEvent)) BackupOption {
+	return func(o *backupOptions) {
+		o.progress = cb
+	}
+}
+
+// BackupChunkSize sets the maximum number of messages stored in every chunk of a stream data backup, defaults to 10000
+func BackupChunkSize(n int) BackupOption {
+	return func(o *backupOptions) {
+		if n > 0 {
+			o.chunkSize = n
+		}
+	}
+}
+
+// BackupServerVersion records the version of the server being backed up in the manifest of backup archives, the
+// NATS connection does not report the version so it has to be supplied by the caller
+func BackupServerVersion(v string) BackupOption {
+	return func(o *backupOptions) {
+		o.version = v
+	}
+}
+
+// BackupConnection sets the connection related options used to communicate with JetStream during backup and restore
+func BackupConnection(opts ...RequestOption) BackupOption {
+	return func(o *backupOptions) {
+		o.ropts = append(o.ropts, opts...)
+	}
+}
+
+// backupTarget is where the files making up a configuration backup are written to
+type backupTarget interface {
+	write(name string, btype string, data []byte) error
+	path(name string) string
+	finish() error
+	abort()
+}
+
+type dirTarget string
+
+func newDirTarget(dir string) (dirTarget, error) {
+	return dirTarget(dir), os.MkdirAll(dir, 0750)
+}
+
+func (d dirTarget) write(name string, _ string, data []byte) error {
+	return ioutil.WriteFile(d.path(name), data, 0640)
+}
+
+func (d dirTarget) path(name string) string { return filepath.Join(string(d), name) }
+func (d dirTarget) finish() error           { return nil }
+func (d dirTarget) abort()                  {}
+
+// BackupJetStreamConfiguration creates a backup of all configuration for Streams, Consumers and Stream Templates,
+// failures to backup individual items do not stop the backup and are returned as BackupErrors.
+//
+// When backupDir ends in .tar.gz or .tgz a single compressed archive holding a BackupManifest is created instead of a directory
+func BackupJetStreamConfiguration(backupDir string, opts ...BackupOption) error {
+	bopts := newBackupOptions(opts...)
+
 	_, err := os.Stat(backupDir)
 	if err == nil || !os.IsNotExist(err) {
 		return fmt.Errorf("%s already exist", backupDir)
 	}
 
-	err = os.MkdirAll(backupDir, 0750)
+	var target backupTarget
+	if isBackupArchive(backupDir) {
+		target, err = newArchiveTarget(backupDir, bopts)
+	} else {
+		target, err = newDirTarget(backupDir)
+	}
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Creating JetStream backup into %s", backupDir)
+	bopts.log.Infof("Creating JetStream backup into %s", backupDir)
+
+	var errs BackupErrors
+
 	err = EachStream(func(stream *Stream) {
-		err = backupStream(stream, backupDir)
-		if err != nil {
-			log.Fatalf("Could not backup Stream %s: %s", stream.Name(), err)
-		}
-	})
+		errs = append(errs, backupStream(stream, target, bopts)...)
+	}, bopts.ropts...)
 	if err != nil {
+		target.abort()
 		return err
 	}
 
 	err = EachStreamTemplate(func(template *StreamTemplate) {
-		err = backupStreamTemplate(template, backupDir)
+		err := backupStreamTemplate(template, target, bopts)
 		if err != nil {
-			log.Fatalf("Could not backup Stream Template %s: %s", template.Name(), err)
+			errs = append(errs, err)
 		}
-	})
+	}, bopts.ropts...)
+	if err != nil {
+		target.abort()
+		return err
+	}
+
+	err = target.finish()
+	if err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		bopts.log.Errorf("Configuration backup completed with %d errors", len(errs))
+		return errs
+	}
 
-	log.Printf("Configuration backup complete")
+	bopts.log.Infof("Configuration backup complete")
 
 	return nil
 }
 
-// RestoreJetStreamConfiguration restores the configuration from a backup made by BackupJetStreamConfiguration
-func RestoreJetStreamConfiguration(backupDir string, update bool) error {
+// RestoreJetStreamConfiguration restores the configuration from a backup made by BackupJetStreamConfiguration, backupDir
+// can be a directory or a backup archive
+func RestoreJetStreamConfiguration(backupDir string, update bool, opts ...BackupOption) error {
+	bopts := newBackupOptions(opts...)
+
+	backups, err := loadBackups(backupDir, bopts)
+	if err != nil {
+		return err
+	}
+
+	eachOfType := func(bt string, cb func(*BackupData) error) error {
+		for _, b := range backups {
+			if b.Type == bt {
+				err := cb(b)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	err = eachOfType("stream", func(d *BackupData) error { return restoreStream(d, update, bopts) })
+	if err != nil {
+		return err
+	}
+
+	err = eachOfType("stream_template", func(d *BackupData) error { return restoreStreamTemplate(d, bopts) })
+	if err != nil {
+		return err
+	}
+
+	err = eachOfType("consumer", func(d *BackupData) error { return restoreConsumer(d, bopts) })
+	if err != nil {
+		return err
+	}
+
+	return err
+}
+
+// loads the backups from a directory or archive
+func loadBackups(backupDir string, bopts *backupOptions) ([]*BackupData, error) {
+	stat, err := os.Stat(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stat.IsDir() {
+		backups, _, err := loadBackupArchive(backupDir, bopts)
+		return backups, err
+	}
+
+	return loadBackupDir(backupDir, bopts)
+}
+
+// loads all backups files since we have to do them in a specific order
+func loadBackupDir(backupDir string, bopts *backupOptions) ([]*BackupData, error) {
 	backups := []*BackupData{}
 
-	// load all backups files since we have to do them in a specific order
 	err := filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -93,7 +319,7 @@ func RestoreJetStreamConfiguration(backupDir string, update bool) error {
 			return nil
 		}
 
-		log.Printf("Reading file %s", path)
+		bopts.log.Debugf("Reading file %s", path)
 		b, err := ioutil.ReadFile(path)
 		if err != nil {
 			return err
@@ -113,44 +339,15 @@ func RestoreJetStreamConfiguration(backupDir string, update bool) error {
 
 		return nil
 	})
-	if err != nil {
-		return err
-	}
 
-	eachOfType := func(bt string, cb func(*BackupData) error) error {
-		for _, b := range backups {
-			if b.Type == bt {
-				err := cb(b)
-				if err != nil {
-					return err
-				}
-			}
-		}
-
-		return nil
-	}
-
-	err = eachOfType("stream", func(d *BackupData) error { return restoreStream(d, update) })
-	if err != nil {
-		return err
-	}
-
-	err = eachOfType("stream_template", restoreStreamTemplate)
-	if err != nil {
-		return err
-	}
-
-	err = eachOfType("consumer", restoreConsumer)
-	if err != nil {
-		return err
-	}
-
-	return err
+	return backups, err
 }
 
 // RestoreJetStreamConfigurationFile restores a single file from a backup made by BackupJetStreamConfiguration
-func RestoreJetStreamConfigurationFile(path string, update bool) error {
-	log.Printf("Reading file %s", path)
+func RestoreJetStreamConfigurationFile(path string, update bool, opts ...BackupOption) error {
+	bopts := newBackupOptions(opts...)
+
+	bopts.log.Debugf("Reading file %s", path)
 	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
@@ -168,11 +365,11 @@ func RestoreJetStreamConfigurationFile(path string, update bool) error {
 
 	switch bd.Type {
 	case "stream":
-		err = restoreStream(bd, update)
+		err = restoreStream(bd, update, bopts)
 	case "consumer":
-		err = restoreConsumer(bd)
+		err = restoreConsumer(bd, bopts)
 	case "stream_template":
-		err = restoreStreamTemplate(bd)
+		err = restoreStreamTemplate(bd, bopts)
 	default:
 		err = fmt.Errorf("unknown backup type %q", bd.Type)
 	}
@@ -180,23 +377,27 @@ func RestoreJetStreamConfigurationFile(path string, update bool) error {
 	return err
 }
 
-func restoreStream(backup *BackupData, update bool) error {
+func restoreStream(backup *BackupData, update bool, bopts *backupOptions) (err error) {
 	if backup.Type != "stream" {
 		return fmt.Errorf("cannot restore backup of type %q as Stream", backup.Type)
 	}
 
 	sc := api.StreamConfig{}
-	err := json.Unmarshal([]byte(backup.Configuration), &sc)
+	err = json.Unmarshal([]byte(backup.Configuration), &sc)
 	if err != nil {
 		return err
 	}
 
 	if sc.Template != "" {
-		log.Printf("Skipping Template managed Stream %s", sc.Name)
+		bopts.log.Infof("Skipping Template managed Stream %s", sc.Name)
+		bopts.report("stream", sc.Name, sc.Name, BackupPhaseSkipped, nil)
 		return nil
 	}
 
-	known, err := IsKnownStream(sc.Name)
+	bopts.report("stream", sc.Name, sc.Name, BackupPhaseStarted, nil)
+	defer func() { bopts.reportResult("stream", sc.Name, sc.Name, err) }()
+
+	known, err := IsKnownStream(sc.Name, bopts.ropts...)
 	if err != nil {
 		return err
 	}
@@ -206,110 +407,125 @@ func restoreStream(backup *BackupData, update bool) error {
 		err = fmt.Errorf("stream %s exists and update was not specified", sc.Name)
 	case known && update:
 		var stream *Stream
-		stream, err = LoadStream(sc.Name)
+		stream, err = LoadStream(sc.Name, bopts.ropts...)
 		if err != nil {
 			return err
 		}
 
-		log.Printf("Updating Stream %s configuration", sc.Name)
+		bopts.log.Infof("Updating Stream %s configuration", sc.Name)
 		err = stream.UpdateConfiguration(sc)
 
 	default:
-		log.Printf("Restoring Stream %s", sc.Name)
-		_, err = NewStreamFromDefault(sc.Name, sc)
+		bopts.log.Infof("Restoring Stream %s", sc.Name)
+		_, err = NewStreamFromDefault(sc.Name, sc, StreamConnection(bopts.ropts...))
 	}
 
 	return err
 }
 
-func restoreStreamTemplate(backup *BackupData) error {
+func restoreStreamTemplate(backup *BackupData, bopts *backupOptions) (err error) {
 	if backup.Type != "stream_template" {
 		return fmt.Errorf("cannot restore backup of type %q as Stream Template", backup.Type)
 	}
 
 	tc := api.StreamTemplateConfig{}
-	err := json.Unmarshal([]byte(backup.Configuration), &tc)
+	err = json.Unmarshal([]byte(backup.Configuration), &tc)
 	if err != nil {
 		return err
 	}
 
 	tc.Config.Name = ""
 
-	log.Printf("Restoring Stream Template %s", tc.Name)
-	_, err = NewStreamTemplate(tc.Name, tc.MaxStreams, *tc.Config)
+	bopts.report("stream_template", "", tc.Name, BackupPhaseStarted, nil)
+	defer func() { bopts.reportResult("stream_template", "", tc.Name, err) }()
+
+	bopts.log.Infof("Restoring Stream Template %s", tc.Name)
+	_, err = NewStreamTemplate(tc.Name, tc.MaxStreams, *tc.Config, StreamConnection(bopts.ropts...))
 	return err
 }
 
-func restoreConsumer(backup *BackupData) error {
+func restoreConsumer(backup *BackupData, bopts *backupOptions) (err error) {
 	if backup.Type != "consumer" {
 		return fmt.Errorf("cannot restore backup of type %q as Consumer", backup.Type)
 	}
 
 	cc := ConsumerBackup{}
-	err := json.Unmarshal([]byte(backup.Configuration), &cc)
+	err = json.Unmarshal([]byte(backup.Configuration), &cc)
 	if err != nil {
 		return err
 	}
 
-	known, err := IsKnownStream(cc.Stream)
+	known, err := IsKnownStream(cc.Stream, bopts.ropts...)
 	if err != nil {
+		bopts.report("consumer", cc.Stream, cc.Name, BackupPhaseFailed, err)
 		return err
 	}
 
 	if !known {
-		log.Printf("Restoring Consumer %s > %s skipped - stream does not exist, possibly managed by a Stream Template", cc.Stream, cc.Name)
+		bopts.log.Warnf("Restoring Consumer %s > %s skipped - stream does not exist, possibly managed by a Stream Template", cc.Stream, cc.Name)
+		bopts.report("consumer", cc.Stream, cc.Name, BackupPhaseSkipped, nil)
 		return nil
 	}
 
-	log.Printf("Restoring Consumer %s > %s", cc.Stream, cc.Name)
-	_, err = NewConsumerFromDefault(cc.Stream, cc.Config)
+	bopts.report("consumer", cc.Stream, cc.Name, BackupPhaseStarted, nil)
+	defer func() { bopts.reportResult("consumer", cc.Stream, cc.Name, err) }()
+
+	bopts.log.Infof("Restoring Consumer %s > %s", cc.Stream, cc.Name)
+	_, err = NewConsumerFromDefault(cc.Stream, cc.Config, ConsumerConnection(bopts.ropts...))
 	return err
 }
 
-func backupStream(stream *Stream, backupDir string) error {
-	path := filepath.Join(backupDir, fmt.Sprintf("stream_%s.json", stream.Name()))
-	log.Printf("Stream %s to %s", stream.Name(), path)
-
-	bupj, err := backupSerialize(stream.Configuration(), "stream")
-	if err != nil {
-		return err
-	}
+func backupStream(stream *Stream, target backupTarget, bopts *backupOptions) (errs []error) {
+	name := fmt.Sprintf("stream_%s.json", stream.Name())
+	bopts.log.Infof("Stream %s to %s", stream.Name(), target.path(name))
+	bopts.report("stream", stream.Name(), stream.Name(), BackupPhaseStarted, nil)
 
-	err = ioutil.WriteFile(path, bupj, 0640)
+	err := writeBackup(target, name, stream.Configuration(), "stream")
+	bopts.reportResult("stream", stream.Name(), stream.Name(), err)
 	if err != nil {
-		return err
+		bopts.log.Errorf("Could not backup Stream %s: %s", stream.Name(), err)
+		return []error{fmt.Errorf("could not backup Stream %s: %w", stream.Name(), err)}
 	}
 
 	err = stream.EachConsumer(func(consumer *Consumer) {
-		err = backupConsumer(consumer, backupDir)
+		err := backupConsumer(consumer, target, bopts)
 		if err != nil {
-			return
+			errs = append(errs, err)
 		}
 	})
+	if err != nil {
+		bopts.log.Errorf("Could not list Consumers for Stream %s: %s", stream.Name(), err)
+		errs = append(errs, fmt.Errorf("could not list Consumers for Stream %s: %w", stream.Name(), err))
+	}
 
-	return err
+	return errs
 }
 
-func backupStreamTemplate(template *StreamTemplate, backupDir string) error {
-	path := filepath.Join(backupDir, fmt.Sprintf("stream_template_%s.json", template.Name()))
-	log.Printf("Stream Template %s to %s", template.Name(), path)
+func backupStreamTemplate(template *StreamTemplate, target backupTarget, bopts *backupOptions) error {
+	name := fmt.Sprintf("stream_template_%s.json", template.Name())
+	bopts.log.Infof("Stream Template %s to %s", template.Name(), target.path(name))
+	bopts.report("stream_template", "", template.Name(), BackupPhaseStarted, nil)
 
-	bupj, err := backupSerialize(template.Configuration(), "stream_template")
+	err := writeBackup(target, name, template.Configuration(), "stream_template")
+	bopts.reportResult("stream_template", "", template.Name(), err)
 	if err != nil {
-		return err
+		bopts.log.Errorf("Could not backup Stream Template %s: %s", template.Name(), err)
+		return fmt.Errorf("could not backup Stream Template %s: %w", template.Name(), err)
 	}
 
-	return ioutil.WriteFile(path, bupj, 0640)
+	return nil
 }
 
-func backupConsumer(consumer *Consumer, backupDir string) error {
+func backupConsumer(consumer *Consumer, target backupTarget, bopts *backupOptions) error {
 	if consumer.IsEphemeral() {
-		log.Printf("Consumer %s > %s skipped", consumer.StreamName(), consumer.Name())
+		bopts.log.Infof("Consumer %s > %s skipped", consumer.StreamName(), consumer.Name())
+		bopts.report("consumer", consumer.StreamName(), consumer.Name(), BackupPhaseSkipped, nil)
 		return nil
 	}
 
-	path := filepath.Join(backupDir, fmt.Sprintf("stream_%s_consumer_%s.json", consumer.StreamName(), consumer.Name()))
-	log.Printf("Consumer %s > %s to %s", consumer.StreamName(), consumer.Name(), path)
+	name := fmt.Sprintf("stream_%s_consumer_%s.json", consumer.StreamName(), consumer.Name())
+	bopts.log.Infof("Consumer %s > %s to %s", consumer.StreamName(), consumer.Name(), target.path(name))
+	bopts.report("consumer", consumer.StreamName(), consumer.Name(), BackupPhaseStarted, nil)
 
 	cb := &ConsumerBackup{
 		Name:   consumer.Name(),
@@ -317,12 +533,23 @@ func backupConsumer(consumer *Consumer, backupDir string) error {
 		Config: consumer.Configuration(),
 	}
 
-	bupj, err := backupSerialize(cb, "consumer")
+	err := writeBackup(target, name, cb, "consumer")
+	bopts.reportResult("consumer", consumer.StreamName(), consumer.Name(), err)
+	if err != nil {
+		bopts.log.Errorf("Could not backup Consumer %s > %s: %s", consumer.StreamName(), consumer.Name(), err)
+		return fmt.Errorf("could not backup Consumer %s > %s: %w", consumer.StreamName(), consumer.Name(), err)
+	}
+
+	return nil
+}
+
+func writeBackup(target backupTarget, name string, data interface{}, btype string) error {
+	bupj, err := backupSerialize(data, btype)
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(path, bupj, 0640)
+	return target.write(name, btype, bupj)
 }
 
 func verifySum(data []byte, csum string) bool {
@@ -0,0 +1,109 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestBackupProgress(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.NewConsumer(jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	sub, err := nc.SubscribeSync("out")
+	checkErr(t, err, "subscribe failed")
+	defer sub.Unsubscribe()
+
+	_, err = stream.NewConsumer(jsm.DeliverySubject("out"))
+	checkErr(t, err, "ephemeral create failed")
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	var events []string
+	progress := func(e jsm.BackupEvent) {
+		events = append(events, fmt.Sprintf("%s %s %s", e.Type, e.Name, e.Phase))
+	}
+
+	var logs bytes.Buffer
+
+	dir := filepath.Join(td, "backup")
+	err = jsm.BackupJetStreamConfiguration(dir, jsm.BackupProgress(progress), jsm.BackupLogger(jsm.NewStdLogger(log.New(&logs, "", 0))))
+	checkErr(t, err, "backup failed")
+
+	if len(events) != 5 || events[0] != "stream ORDERS started" || events[1] != "stream ORDERS completed" {
+		t.Fatalf("invalid events %v", events)
+	}
+
+	consumers := strings.Join(events[2:], ",")
+	if !strings.Contains(consumers, "consumer NEW started,consumer NEW completed") || !strings.Contains(consumers, "skipped") {
+		t.Fatalf("invalid consumer events %v", events)
+	}
+
+	if !strings.Contains(logs.String(), "Configuration backup complete") {
+		t.Fatalf("expected completion to be logged: %s", logs.String())
+	}
+
+	err = stream.Delete()
+	checkErr(t, err, "delete failed")
+
+	events = []string{}
+	err = jsm.RestoreJetStreamConfiguration(dir, false, jsm.BackupProgress(progress))
+	checkErr(t, err, "restore failed")
+
+	expected := []string{"stream ORDERS started", "stream ORDERS completed", "consumer NEW started", "consumer NEW completed"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v got %v", expected, events)
+	}
+
+	events = []string{}
+	err = jsm.RestoreJetStreamConfiguration(dir, false, jsm.BackupProgress(progress))
+	if err == nil {
+		t.Fatalf("expected restore of an existing stream to fail")
+	}
+
+	if len(events) != 2 || events[1] != "stream ORDERS failed" {
+		t.Fatalf("expected a failed event got %v", events)
+	}
+}
+
+func TestBackupErrors(t *testing.T) {
+	var err error = jsm.BackupErrors{errors.New("one"), errors.New("two")}
+	if err.Error() != "2 errors during backup: one, two" {
+		t.Fatalf("invalid error: %s", err)
+	}
+
+	var berr jsm.BackupErrors
+	if !errors.As(err, &berr) || len(berr) != 2 {
+		t.Fatalf("expected BackupErrors")
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// MessageMatcher decides if a message should be deleted
+type MessageMatcher func(msg *ReaderMsg) (bool, error)
+
+// DeleteOption configures a bulk delete
+type DeleteOption func(o *deleteOptions)
+
+type deleteOptions struct {
+	filter      string
+	since       time.Time
+	until       time.Time
+	matchers    []MessageMatcher
+	concurrency int
+	dryRun      bool
+	ctx         context.Context
+}
+
+// DeleteSubject only deletes messages matching subject, wildcards are supported
+func DeleteSubject(subject string) DeleteOption {
+	return func(o *deleteOptions) {
+		o.filter = subject
+	}
+}
+
+// DeleteTimeRange only deletes messages received between since and until, a zero time leaves that side open
+func DeleteTimeRange(since time.Time, until time.Time) DeleteOption {
+	return func(o *deleteOptions) {
+		o.since = since
+		o.until = until
+	}
+}
+
+// DeleteMatching only deletes messages for which m returns true, when given more than once all have to match
+func DeleteMatching(m MessageMatcher) DeleteOption {
+	return func(o *deleteOptions) {
+		o.matchers = append(o.matchers, m)
+	}
+}
+
+// DeleteJSONFieldMatch only deletes messages with a JSON body where field equals value, nested fields are
+// separated by dots like customer.email. Bodies that are not JSON objects do not match
+func DeleteJSONFieldMatch(field string, value string) DeleteOption {
+	path := strings.Split(field, ".")
+
+	return DeleteMatching(func(msg *ReaderMsg) (bool, error) {
+		var body interface{}
+		if json.Unmarshal(msg.Data, &body) != nil {
+			return false, nil
+		}
+
+		for _, p := range path {
+			obj, ok := body.(map[string]interface{})
+			if !ok {
+				return false, nil
+			}
+
+			body, ok = obj[p]
+			if !ok {
+				return false, nil
+			}
+		}
+
+		switch v := body.(type) {
+		case string:
+			return v == value, nil
+		case nil, map[string]interface{}, []interface{}:
+			return false, nil
+		default:
+			return fmt.Sprintf("%v", v) == value, nil
+		}
+	})
+}
+
+// DeleteConcurrency sets how many delete requests may be in flight at once, defaults to 10
+func DeleteConcurrency(c int) DeleteOption {
+	return func(o *deleteOptions) {
+		if c > 0 {
+			o.concurrency = c
+		}
+	}
+}
+
+// DeleteDryRun scans the Stream and reports what would be deleted without removing anything
+func DeleteDryRun() DeleteOption {
+	return func(o *deleteOptions) {
+		o.dryRun = true
+	}
+}
+
+// DeleteContext sets a context that stops the scan and deletes when done
+func DeleteContext(ctx context.Context) DeleteOption {
+	return func(o *deleteOptions) {
+		o.ctx = ctx
+	}
+}
+
+// DeletedMessage is a message removed by a bulk delete
+type DeletedMessage struct {
+	Sequence uint64    `json:"seq"`
+	Subject  string    `json:"subject"`
+	Time     time.Time `json:"time"`
+	Size     int       `json:"size"`
+	// Checksum is the hex encoded SHA256 of the message body that was removed
+	Checksum string `json:"checksum"`
+}
+
+// DeleteFailure is a matching message that could not be deleted
+type DeleteFailure struct {
+	Sequence uint64 `json:"seq"`
+	Error    string `json:"error"`
+}
+
+// DeletionReport is an audit record of a bulk delete, it holds no message bodies
+type DeletionReport struct {
+	Stream   string           `json:"stream"`
+	Filter   string           `json:"filter,omitempty"`
+	Since    time.Time        `json:"since,omitempty"`
+	Until    time.Time        `json:"until,omitempty"`
+	Started  time.Time        `json:"started"`
+	Finished time.Time        `json:"finished"`
+	DryRun   bool             `json:"dry_run"`
+	Scanned  uint64           `json:"scanned"`
+	Deleted  []DeletedMessage `json:"deleted"`
+	Failed   []DeleteFailure  `json:"failed,omitempty"`
+	// Missing are matching messages that were already removed when they were about to be deleted, by limits or by
+	// someone else, their bodies are gone but they were not deleted by this operation
+	Missing []uint64 `json:"missing,omitempty"`
+}
+
+// DeleteMessages scans the Stream and deletes every message matching all the given criteria, the report lists
+// deleted messages in sequence order. Messages that fail to delete are recorded in the report and do not stop
+// the operation, an error is returned when the scan fails or some messages could not be deleted
+func (s *Stream) DeleteMessages(opts ...DeleteOption) (*DeletionReport, error) {
+	dopts := &deleteOptions{concurrency: 10, ctx: context.Background()}
+	for _, opt := range opts {
+		opt(dopts)
+	}
+
+	report := &DeletionReport{
+		Stream:  s.Name(),
+		Filter:  dopts.filter,
+		Since:   dopts.since,
+		Until:   dopts.until,
+		Started: time.Now(),
+		DryRun:  dopts.dryRun,
+		Deleted: []DeletedMessage{},
+	}
+
+	matches, err := s.scanForDelete(dopts, report)
+	if err != nil {
+		report.Finished = time.Now()
+		return report, err
+	}
+
+	if dopts.dryRun {
+		report.Deleted = matches
+		report.Finished = time.Now()
+		return report, nil
+	}
+
+	s.deleteConcurrently(dopts, matches, report)
+	report.Finished = time.Now()
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("%d of %d matching messages could not be deleted", len(report.Failed), len(matches))
+	}
+
+	return report, dopts.ctx.Err()
+}
+
+func (s *Stream) scanForDelete(dopts *deleteOptions, report *DeletionReport) ([]DeletedMessage, error) {
+	var ropts []StreamReaderOption
+	if dopts.filter != "" {
+		ropts = append(ropts, ReaderFilter(dopts.filter))
+	}
+
+	// the start time is checked here rather than by the reader as the server can not find start times in
+	// streams with deleted messages, which any stream this is used on repeatedly will have
+	if !dopts.until.IsZero() {
+		ropts = append(ropts, ReaderEndTime(dopts.until))
+	}
+
+	reader, err := s.Reader(dopts.ctx, ropts...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	matches := []DeletedMessage{}
+
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			return matches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		report.Scanned++
+
+		if !dopts.since.IsZero() && msg.Time.Before(dopts.since) {
+			continue
+		}
+
+		ok, err := dopts.match(msg)
+		if err != nil {
+			return nil, fmt.Errorf("matching message %d failed: %w", msg.Sequence, err)
+		}
+		if !ok {
+			continue
+		}
+
+		matches = append(matches, DeletedMessage{
+			Sequence: msg.Sequence,
+			Subject:  msg.Subject,
+			Time:     msg.Time,
+			Size:     len(msg.Data),
+			Checksum: fmt.Sprintf("%x", sha256.Sum256(msg.Data)),
+		})
+	}
+}
+
+func (o *deleteOptions) match(msg *ReaderMsg) (bool, error) {
+	for _, m := range o.matchers {
+		ok, err := m(msg)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (s *Stream) deleteConcurrently(dopts *deleteOptions, matches []DeletedMessage, report *DeletionReport) {
+	work := make(chan DeletedMessage)
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < dopts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for msg := range work {
+				err := s.DeleteMessage(int(msg.Sequence))
+
+				mu.Lock()
+				switch {
+				case errors.Is(err, api.ErrMessageNotFound):
+					report.Missing = append(report.Missing, msg.Sequence)
+				case err != nil:
+					report.Failed = append(report.Failed, DeleteFailure{Sequence: msg.Sequence, Error: err.Error()})
+				default:
+					report.Deleted = append(report.Deleted, msg)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, msg := range matches {
+		if dopts.ctx.Err() != nil {
+			break
+		}
+
+		work <- msg
+	}
+	close(work)
+
+	wg.Wait()
+
+	sort.Slice(report.Deleted, func(i, j int) bool { return report.Deleted[i].Sequence < report.Deleted[j].Sequence })
+	sort.Slice(report.Failed, func(i, j int) bool { return report.Failed[i].Sequence < report.Failed[j].Sequence })
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i] < report.Missing[j] })
+}
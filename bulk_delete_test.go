@@ -0,0 +1,135 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestStream_DeleteMessages(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	var since time.Time
+	for i := 1; i <= 10; i++ {
+		if i == 3 {
+			time.Sleep(20 * time.Millisecond)
+			since = time.Now()
+		}
+
+		email := "other@example.net"
+		if i%2 == 1 {
+			email = "user@example.net"
+		}
+
+		_, err = stream.Publish("ORDERS.new", []byte(fmt.Sprintf(`{"id":%d,"customer":{"email":%q}}`, i, email)))
+		checkErr(t, err, "publish failed")
+	}
+
+	_, err = stream.Publish("ORDERS.processed", []byte(`{"id":11,"customer":{"email":"user@example.net"}}`))
+	checkErr(t, err, "publish failed")
+	_, err = stream.Publish("ORDERS.new", []byte(`not json`))
+	checkErr(t, err, "publish failed")
+
+	opts := []jsm.DeleteOption{
+		jsm.DeleteSubject("ORDERS.new"),
+		jsm.DeleteTimeRange(since, time.Time{}),
+		jsm.DeleteJSONFieldMatch("customer.email", "user@example.net"),
+		jsm.DeleteConcurrency(2),
+	}
+
+	dry, err := stream.DeleteMessages(append(opts, jsm.DeleteDryRun())...)
+	checkErr(t, err, "dry run failed")
+	if !dry.DryRun || dry.Scanned != 11 || len(dry.Deleted) != 4 {
+		t.Fatalf("unexpected dry run report: %+v", dry)
+	}
+
+	state, err := stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 12 {
+		t.Fatalf("dry run deleted messages: %+v", state)
+	}
+
+	report, err := stream.DeleteMessages(opts...)
+	checkErr(t, err, "delete failed")
+	if report.DryRun || len(report.Deleted) != 4 || len(report.Failed) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	for i, seq := range []uint64{3, 5, 7, 9} {
+		deleted := report.Deleted[i]
+		body := fmt.Sprintf(`{"id":%d,"customer":{"email":"user@example.net"}}`, seq)
+
+		if deleted.Sequence != seq || deleted.Subject != "ORDERS.new" || deleted.Size != len(body) {
+			t.Fatalf("unexpected deleted message %d: %+v", i, deleted)
+		}
+
+		if deleted.Checksum != fmt.Sprintf("%x", sha256.Sum256([]byte(body))) {
+			t.Fatalf("invalid checksum for %d", seq)
+		}
+
+		_, err = stream.LoadMessage(int(seq))
+		if !errors.Is(err, api.ErrMessageNotFound) {
+			t.Fatalf("message %d was not deleted: %v", seq, err)
+		}
+	}
+
+	state, err = stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 8 {
+		t.Fatalf("expected 8 messages left: %+v", state)
+	}
+
+	_, err = stream.DeleteMessages(jsm.DeleteMatching(func(_ *jsm.ReaderMsg) (bool, error) { return false, fmt.Errorf("simulated") }))
+	if err == nil {
+		t.Fatalf("expected matcher error")
+	}
+
+	report, err = stream.DeleteMessages(opts...)
+	checkErr(t, err, "delete failed")
+	if len(report.Deleted) != 0 {
+		t.Fatalf("expected nothing deleted the second time: %+v", report)
+	}
+
+	dry, err = stream.DeleteMessages(append(opts, jsm.DeleteDryRun())...)
+	checkErr(t, err, "dry run failed")
+	dj, err := json.Marshal(dry)
+	checkErr(t, err, "marshal failed")
+	if !strings.Contains(string(dj), `"deleted":[]`) {
+		t.Fatalf("expected an empty deleted list: %s", dj)
+	}
+
+	// removed by someone else between the scan and the delete
+	report, err = stream.DeleteMessages(jsm.DeleteSubject("ORDERS.processed"), jsm.DeleteMatching(func(msg *jsm.ReaderMsg) (bool, error) {
+		return true, stream.DeleteMessage(int(msg.Sequence))
+	}))
+	checkErr(t, err, "delete failed")
+	if len(report.Deleted) != 0 || len(report.Failed) != 0 || !reflect.DeepEqual(report.Missing, []uint64{11}) {
+		t.Fatalf("expected message 11 to be missing: %+v", report)
+	}
+}
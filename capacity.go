@@ -0,0 +1,287 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// CapacityLimit is a limit that causes a Stream to discard or reject messages once reached
+type CapacityLimit string
+
+const (
+	// LimitMaxMsgs is the Stream MaxMsgs limit
+	LimitMaxMsgs CapacityLimit = "max_msgs"
+	// LimitMaxBytes is the Stream MaxBytes limit
+	LimitMaxBytes CapacityLimit = "max_bytes"
+	// LimitMaxAge is the Stream MaxAge limit
+	LimitMaxAge CapacityLimit = "max_age"
+	// LimitAccountMemory is the account limit on memory storage shared by all Streams
+	LimitAccountMemory CapacityLimit = "account_memory"
+	// LimitAccountStorage is the account limit on file storage shared by all Streams
+	LimitAccountStorage CapacityLimit = "account_storage"
+)
+
+// StreamSample is the state of a Stream at a point in time
+type StreamSample struct {
+	Time  time.Time       `json:"time"`
+	State api.StreamState `json:"state"`
+	// FirstTime is when the first message in the Stream was received, zero when unknown
+	FirstTime time.Time `json:"first_time,omitempty"`
+}
+
+// LimitForecast is the projection for a single limit
+type LimitForecast struct {
+	Limit CapacityLimit `json:"limit"`
+	// Usage is how much of the limit is used, 1 or more means the limit was reached
+	Usage float64 `json:"usage"`
+	// Detail describes the usage in the units of the limit
+	Detail string `json:"detail"`
+	// Reached means messages are already being discarded or rejected because of this limit
+	Reached bool `json:"reached"`
+	// Projected means the limit will be reached at the current growth rate, after TimeToLimit
+	Projected   bool          `json:"projected"`
+	TimeToLimit time.Duration `json:"time_to_limit"`
+}
+
+// CapacityForecast projects when a Stream will reach its limits based on its growth while sampled
+type CapacityForecast struct {
+	Stream  string          `json:"stream"`
+	Storage api.StorageType `json:"storage"`
+	// Period is the time covered by the samples
+	Period time.Duration `json:"period"`
+	Msgs   uint64        `json:"messages"`
+	Bytes  uint64        `json:"bytes"`
+	// MsgRate and ByteRate are the net growth of the Stream per second, negative when it shrinks
+	MsgRate  float64 `json:"msg_rate"`
+	ByteRate float64 `json:"byte_rate"`
+	// IngestRate is the number of messages received per second, including those since removed
+	IngestRate float64 `json:"ingest_rate"`
+	// Limits are the projections for every configured limit
+	Limits []LimitForecast `json:"limits"`
+	// First is the limit that was reached or will be reached first, nil when none will be reached
+	First *LimitForecast `json:"first,omitempty"`
+}
+
+// ForecastStreamCapacity projects when a Stream with configuration cfg will reach its limits using at least two
+// samples of its state. Growth is calculated using a least squares fit over all samples. When account is not nil the
+// account storage limit for the Stream storage type is included, other Streams are assumed not to grow
+func ForecastStreamCapacity(cfg api.StreamConfig, account *api.JetStreamAccountStats, samples []StreamSample) (*CapacityForecast, error) {
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("at least 2 samples are required to forecast capacity")
+	}
+
+	first := samples[0]
+	last := samples[len(samples)-1]
+
+	f := &CapacityForecast{
+		Stream:  cfg.Name,
+		Storage: cfg.Storage,
+		Period:  last.Time.Sub(first.Time),
+		Msgs:    last.State.Msgs,
+		Bytes:   last.State.Bytes,
+		Limits:  []LimitForecast{},
+	}
+
+	if f.Period <= 0 {
+		return nil, fmt.Errorf("samples have to be taken over a period of time")
+	}
+
+	f.MsgRate = sampleSlope(samples, func(s api.StreamState) float64 { return float64(s.Msgs) })
+	f.ByteRate = sampleSlope(samples, func(s api.StreamState) float64 { return float64(s.Bytes) })
+	f.IngestRate = sampleSlope(samples, func(s api.StreamState) float64 { return float64(s.LastSeq) })
+
+	if cfg.MaxMsgs > 0 {
+		f.Limits = append(f.Limits, forecastLimit(LimitMaxMsgs, float64(f.Msgs), float64(cfg.MaxMsgs), f.MsgRate, fmt.Sprintf("%d of %d messages", f.Msgs, cfg.MaxMsgs)))
+	}
+
+	if cfg.MaxBytes > 0 {
+		f.Limits = append(f.Limits, forecastLimit(LimitMaxBytes, float64(f.Bytes), float64(cfg.MaxBytes), f.ByteRate, fmt.Sprintf("%d of %d bytes", f.Bytes, cfg.MaxBytes)))
+	}
+
+	if cfg.MaxAge > 0 && f.Msgs > 0 && !last.FirstTime.IsZero() {
+		age := last.Time.Sub(last.FirstTime)
+		lf := LimitForecast{
+			Limit:     LimitMaxAge,
+			Usage:     float64(age) / float64(cfg.MaxAge),
+			Detail:    fmt.Sprintf("oldest message is %v of %v", age.Round(time.Second), cfg.MaxAge),
+			Reached:   age >= cfg.MaxAge,
+			Projected: true,
+		}
+
+		if !lf.Reached {
+			lf.TimeToLimit = cfg.MaxAge - age
+		}
+
+		f.Limits = append(f.Limits, lf)
+	}
+
+	if account != nil {
+		limit, used, name := account.Limits.MaxStore, account.Store, LimitAccountStorage
+		if cfg.Storage == api.MemoryStorage {
+			limit, used, name = account.Limits.MaxMemory, account.Memory, LimitAccountMemory
+		}
+
+		if limit > 0 {
+			f.Limits = append(f.Limits, forecastLimit(name, float64(used), float64(limit), f.ByteRate, fmt.Sprintf("%d of %d bytes used by the account", used, limit)))
+		}
+	}
+
+	for i := range f.Limits {
+		l := &f.Limits[i]
+		if !l.Projected {
+			continue
+		}
+
+		if f.First == nil || l.TimeToLimit < f.First.TimeToLimit {
+			f.First = l
+		}
+	}
+
+	return f, nil
+}
+
+func forecastLimit(limit CapacityLimit, current float64, max float64, rate float64, detail string) LimitForecast {
+	lf := LimitForecast{
+		Limit:   limit,
+		Usage:   current / max,
+		Detail:  detail,
+		Reached: current >= max,
+	}
+
+	switch {
+	case lf.Reached:
+		lf.Projected = true
+	case rate > 0:
+		lf.Projected = true
+		lf.TimeToLimit = time.Duration((max - current) / rate * float64(time.Second))
+	}
+
+	return lf
+}
+
+// sampleSlope is the least squares rate of change per second of a value over the samples
+func sampleSlope(samples []StreamSample, value func(api.StreamState) float64) float64 {
+	start := samples[0].Time
+	n := float64(len(samples))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Time.Sub(start).Seconds()
+		y := value(s.State)
+
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// CapacityOption configures how a Stream is sampled for a capacity forecast
+type CapacityOption func(o *capacityOptions)
+
+type capacityOptions struct {
+	samples  int
+	interval time.Duration
+}
+
+// CapacitySamples sets how many samples to take, defaults to 6
+func CapacitySamples(n int) CapacityOption {
+	return func(o *capacityOptions) {
+		if n >= 2 {
+			o.samples = n
+		}
+	}
+}
+
+// CapacityInterval sets the time between samples, defaults to 10 seconds
+func CapacityInterval(d time.Duration) CapacityOption {
+	return func(o *capacityOptions) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// Sample retrieves the current state of the Stream including the time of its first message
+func (s *Stream) Sample() (*StreamSample, error) {
+	state, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &StreamSample{Time: time.Now(), State: state}
+	if state.Msgs == 0 {
+		return sample, nil
+	}
+
+	msg, err := s.LoadMessage(int(state.FirstSeq))
+	switch {
+	case errors.Is(err, api.ErrMessageNotFound):
+		// removed since the state was retrieved, the age is unknown
+	case err != nil:
+		return nil, err
+	default:
+		sample.FirstTime = msg.Time
+	}
+
+	return sample, nil
+}
+
+// ForecastCapacity samples the Stream state and projects when it will reach its limits, including the account
+// storage limits, see ForecastStreamCapacity. Sampling blocks for the interval between each of the samples
+func (s *Stream) ForecastCapacity(ctx context.Context, opts ...CapacityOption) (*CapacityForecast, error) {
+	copts := &capacityOptions{samples: 6, interval: 10 * time.Second}
+	for _, opt := range opts {
+		opt(copts)
+	}
+
+	var samples []StreamSample
+
+	for i := 0; i < copts.samples; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(copts.interval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		sample, err := s.Sample()
+		if err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, *sample)
+	}
+
+	account, err := JetStreamAccountInfo(s.cfg.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ForecastStreamCapacity(s.Configuration(), &account, samples)
+}
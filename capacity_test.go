@@ -0,0 +1,131 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestForecastStreamCapacity(t *testing.T) {
+	cfg := jsm.DefaultStream
+	cfg.Name = "ORDERS"
+	cfg.MaxMsgs = 1000
+	cfg.MaxBytes = 100000
+	cfg.MaxAge = time.Hour
+
+	now := time.Now()
+	var samples []jsm.StreamSample
+
+	// 10 messages of 50 bytes per second
+	for i := 0; i <= 10; i++ {
+		samples = append(samples, jsm.StreamSample{
+			Time:      now.Add(time.Duration(i) * time.Second),
+			State:     api.StreamState{Msgs: uint64(100 + i*10), Bytes: uint64(5000 + i*500), FirstSeq: 1, LastSeq: uint64(100 + i*10)},
+			FirstTime: now.Add(-50 * time.Minute),
+		})
+	}
+
+	_, err := jsm.ForecastStreamCapacity(cfg, nil, samples[:1])
+	if err == nil {
+		t.Fatalf("expected an error with one sample")
+	}
+
+	account := &api.JetStreamAccountStats{Memory: 10000, Store: 60000, Limits: api.JetStreamAccountLimits{MaxMemory: -1, MaxStore: 100000}}
+
+	f, err := jsm.ForecastStreamCapacity(cfg, account, samples)
+	checkErr(t, err, "forecast failed")
+
+	if f.Period != 10*time.Second || f.Msgs != 200 || f.Bytes != 10000 {
+		t.Fatalf("unexpected forecast %+v", f)
+	}
+
+	if int(f.MsgRate) != 10 || int(f.ByteRate) != 500 || int(f.IngestRate) != 10 {
+		t.Fatalf("unexpected rates %+v", f)
+	}
+
+	if len(f.Limits) != 4 {
+		t.Fatalf("expected 4 limits got %+v", f.Limits)
+	}
+
+	expected := map[jsm.CapacityLimit]time.Duration{
+		jsm.LimitMaxMsgs:        80 * time.Second,
+		jsm.LimitMaxBytes:       180 * time.Second,
+		jsm.LimitMaxAge:         10*time.Minute - 10*time.Second,
+		jsm.LimitAccountStorage: 80 * time.Second,
+	}
+
+	for _, l := range f.Limits {
+		if !l.Projected || l.Reached || l.TimeToLimit.Round(time.Second) != expected[l.Limit] {
+			t.Fatalf("unexpected %s forecast %+v", l.Limit, l)
+		}
+	}
+
+	if f.First == nil || f.First.Limit != jsm.LimitMaxMsgs {
+		t.Fatalf("expected max_msgs to be reached first got %+v", f.First)
+	}
+
+	// at the limit the stream stops growing
+	for i := range samples {
+		samples[i].State.Msgs = 1000
+		samples[i].State.Bytes = 50000
+	}
+
+	f, err = jsm.ForecastStreamCapacity(cfg, nil, samples)
+	checkErr(t, err, "forecast failed")
+
+	if f.First == nil || f.First.Limit != jsm.LimitMaxMsgs || !f.First.Reached || f.First.TimeToLimit != 0 {
+		t.Fatalf("expected max_msgs to be reached got %+v", f.First)
+	}
+
+	for _, l := range f.Limits {
+		if l.Limit == jsm.LimitMaxBytes && l.Projected {
+			t.Fatalf("max_bytes should not be projected without growth: %+v", l)
+		}
+	}
+}
+
+func TestStream_ForecastCapacity(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.MaxMessages(1000), jsm.MaxAge(time.Hour))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		for i := 0; i < 20 && ctx.Err() == nil; i++ {
+			stream.Publish("ORDERS.new", []byte("hello"))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	f, err := stream.ForecastCapacity(ctx, jsm.CapacitySamples(3), jsm.CapacityInterval(50*time.Millisecond))
+	checkErr(t, err, "forecast failed")
+
+	if f.Stream != "ORDERS" || f.Storage != api.MemoryStorage || f.Msgs == 0 || f.MsgRate <= 0 {
+		t.Fatalf("unexpected forecast %+v", f)
+	}
+
+	if f.First == nil || f.First.Limit != jsm.LimitMaxMsgs || !f.First.Projected {
+		t.Fatalf("expected max_msgs to be reached first got %+v", f.First)
+	}
+}
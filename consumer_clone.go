@@ -0,0 +1,86 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clone creates a new Consumer on the same Stream using this Consumer configuration modified by opts. The durable
+// name is not copied, without a DurableName() option an ephemeral Consumer is made which is only possible for push
+// based Consumers. Push based clones require a new subject using DeliverySubject()
+func (c *Consumer) Clone(opts ...ConsumerOption) (*Consumer, error) {
+	cfg := c.Configuration()
+	cfg.Durable = ""
+
+	opts = append(c.cloneOpts(), opts...)
+
+	// sharing the delivery subject would deliver the replayed messages to the subscribers of this Consumer
+	if c.IsPushMode() {
+		ncfg, err := NewConsumerConfiguration(cfg, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		if ncfg.DeliverSubject == cfg.DeliverSubject {
+			return nil, fmt.Errorf("push based clones require a delivery subject other than %s", cfg.DeliverSubject)
+		}
+	}
+
+	return NewConsumerFromDefault(c.stream, cfg, opts...)
+}
+
+// CloneAtSequence clones the Consumer starting at stream sequence seq, see Clone
+func (c *Consumer) CloneAtSequence(seq uint64, opts ...ConsumerOption) (*Consumer, error) {
+	return c.Clone(append([]ConsumerOption{StartAtSequence(seq)}, opts...)...)
+}
+
+// CloneAtTime clones the Consumer starting with the first message received at or after t, see Clone
+func (c *Consumer) CloneAtTime(t time.Time, opts ...ConsumerOption) (*Consumer, error) {
+	return c.Clone(append([]ConsumerOption{StartAtTime(t)}, opts...)...)
+}
+
+// CloneAtAckFloor clones the Consumer starting after its ack floor so all messages it did not acknowledge are
+// delivered, when it never delivered any messages its delivery policy is kept, see Clone
+func (c *Consumer) CloneAtAckFloor(opts ...ConsumerOption) (*Consumer, error) {
+	start, err := c.ackFloorStart()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Clone(append(start, opts...)...)
+}
+
+// ackFloorStart is the option that positions a new consumer after the ack floor of c
+func (c *Consumer) ackFloorStart() ([]ConsumerOption, error) {
+	state, err := c.State()
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Delivered.ConsumerSeq == 0 {
+		return nil, nil
+	}
+
+	return []ConsumerOption{StartAtSequence(state.AckFloor.StreamSeq + 1)}, nil
+}
+
+func (c *Consumer) cloneOpts() []ConsumerOption {
+	if len(c.cfg.ropts) == 0 {
+		return nil
+	}
+
+	return []ConsumerOption{ConsumerConnection(c.cfg.ropts...)}
+}
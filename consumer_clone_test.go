@@ -0,0 +1,139 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestConsumer_Clone(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("ORIG"), jsm.DeliverAllAvailable(), jsm.AckWait(time.Minute), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+
+	var seqs []uint64
+	var mid time.Time
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			time.Sleep(20 * time.Millisecond)
+			mid = time.Now()
+		}
+
+		ack, err := stream.Publish("ORDERS.new", []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+		seqs = append(seqs, ack.Sequence)
+	}
+
+	firstSeq := func(c *jsm.Consumer) int {
+		t.Helper()
+
+		msg, err := c.NextJSMsg()
+		checkErr(t, err, "next failed")
+		info, err := msg.Metadata()
+		checkErr(t, err, "metadata failed")
+
+		return info.StreamSequence()
+	}
+
+	clone, err := consumer.CloneAtSequence(seqs[3], jsm.DurableName("AT_SEQ"))
+	checkErr(t, err, "clone failed")
+	if !clone.IsDurable() || clone.Name() != "AT_SEQ" || clone.AckWait() != time.Minute || clone.FilterSubject() != "ORDERS.new" {
+		t.Fatalf("configuration was not copied: %+v", clone.Configuration())
+	}
+	if seq := firstSeq(clone); seq != int(seqs[3]) {
+		t.Fatalf("expected sequence %d got %d", seqs[3], seq)
+	}
+
+	clone, err = consumer.CloneAtTime(mid, jsm.DurableName("AT_TIME"))
+	checkErr(t, err, "clone failed")
+	if seq := firstSeq(clone); seq != int(seqs[5]) {
+		t.Fatalf("expected sequence %d got %d", seqs[5], seq)
+	}
+
+	// never delivered so it keeps delivering all
+	clone, err = consumer.CloneAtAckFloor(jsm.DurableName("FLOOR_NEW"))
+	checkErr(t, err, "clone failed")
+	if clone.DeliverPolicy() != api.DeliverAll {
+		t.Fatalf("expected deliver all got %s", clone.DeliverPolicy())
+	}
+
+	for i := 0; i < 3; i++ {
+		msg, err := consumer.NextJSMsg()
+		checkErr(t, err, "next failed")
+		checkErr(t, msg.AckSync(), "ack failed")
+	}
+
+	var state api.ConsumerState
+	for i := 0; i < 20; i++ {
+		state, err = consumer.State()
+		checkErr(t, err, "state failed")
+		if state.AckFloor.StreamSeq == state.Delivered.StreamSeq {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	clone, err = consumer.CloneAtAckFloor(jsm.DurableName("FLOOR"))
+	checkErr(t, err, "clone failed")
+	if seq := firstSeq(clone); seq != int(state.AckFloor.StreamSeq+1) {
+		t.Fatalf("expected sequence %d got %d", state.AckFloor.StreamSeq+1, seq)
+	}
+
+	push, err := consumer.Clone(jsm.DurableName("PUSH"), jsm.DeliverySubject("out"))
+	checkErr(t, err, "clone failed")
+
+	// the clone would deliver to the subscribers of the original
+	_, err = push.Clone(jsm.DurableName("SAME"))
+	if err == nil {
+		t.Fatalf("expected a push clone without a delivery subject to fail")
+	}
+
+	_, err = push.CloneAtSequence(seqs[8], jsm.DurableName("SAME"), jsm.DeliverySubject("out"))
+	if err == nil {
+		t.Fatalf("expected a push clone on the same delivery subject to fail")
+	}
+
+	known, err := jsm.IsKnownConsumer("ORDERS", "SAME")
+	checkErr(t, err, "known failed")
+	if known {
+		t.Fatalf("the failed clone was created")
+	}
+
+	// ephemeral consumers need interest on their delivery subject
+	sub, err := nc.SubscribeSync("replay")
+	checkErr(t, err, "subscribe failed")
+	defer sub.Unsubscribe()
+
+	replay, err := push.CloneAtSequence(seqs[8], jsm.DeliverySubject("replay"))
+	checkErr(t, err, "clone failed")
+	if replay.IsDurable() || replay.DeliverySubject() != "replay" {
+		t.Fatalf("expected an ephemeral push clone got %+v", replay.Configuration())
+	}
+
+	msg, err := sub.NextMsg(time.Second)
+	checkErr(t, err, "next failed")
+	if string(msg.Data) != "8" {
+		t.Fatalf("expected message 8 got %q", msg.Data)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// ConsumerHealth is the classification of a Consumer based on its lag
+type ConsumerHealth string
+
+const (
+	// ConsumerHealthy means no thresholds were exceeded
+	ConsumerHealthy ConsumerHealth = "healthy"
+	// ConsumerWarning means at least one warning threshold was exceeded
+	ConsumerWarning ConsumerHealth = "warning"
+	// ConsumerCritical means at least one critical threshold was exceeded
+	ConsumerCritical ConsumerHealth = "critical"
+)
+
+// ConsumerHealthThresholds configures how ConsumerLag is classified, a threshold of 0 disables that check
+type ConsumerHealthThresholds struct {
+	// PendingWarning and PendingCritical are the number of messages not yet delivered
+	PendingWarning  uint64
+	PendingCritical uint64
+	// AckPendingWarning and AckPendingCritical are the number of messages delivered but not acknowledged
+	AckPendingWarning  int
+	AckPendingCritical int
+	// RedeliveredWarning and RedeliveredCritical are the number of messages that were delivered more than once
+	RedeliveredWarning  int
+	RedeliveredCritical int
+	// OldestPendingWarning and OldestPendingCritical is how long the oldest unacknowledged message has been waiting
+	OldestPendingWarning  time.Duration
+	OldestPendingCritical time.Duration
+}
+
+// DefaultConsumerHealthThresholds are the thresholds used by Consumer.Lag()
+var DefaultConsumerHealthThresholds = ConsumerHealthThresholds{
+	PendingWarning:        1000,
+	PendingCritical:       10000,
+	OldestPendingWarning:  time.Minute,
+	OldestPendingCritical: 5 * time.Minute,
+}
+
+// ConsumerLag describes how far a Consumer is behind its Stream
+type ConsumerLag struct {
+	Stream   string `json:"stream"`
+	Consumer string `json:"consumer"`
+	// StreamLastSeq is the last sequence in the Stream
+	StreamLastSeq uint64 `json:"stream_last_seq"`
+	// Delivered is the last message delivered by the Consumer
+	Delivered api.SequencePair `json:"delivered"`
+	// AckFloor is the message up to which all messages are acknowledged
+	AckFloor api.SequencePair `json:"ack_floor"`
+	// Pending is the number of messages in the Stream not yet delivered, for filtered Consumers this includes
+	// messages that do not match the filter
+	Pending uint64 `json:"pending"`
+	// AckFloorGap is the number of messages between the ack floor and the last delivered message
+	AckFloorGap uint64 `json:"ack_floor_gap"`
+	// AckPending is the number of messages delivered but not acknowledged
+	AckPending int `json:"ack_pending"`
+	// Redelivered is the number of outstanding messages that were delivered more than once
+	Redelivered int `json:"redelivered"`
+	// Redeliveries is the total number of redeliveries of outstanding messages
+	Redeliveries uint64 `json:"redeliveries"`
+	// OldestPending is how long the oldest unacknowledged message has been waiting since it was delivered
+	OldestPending time.Duration `json:"oldest_pending"`
+	// Health is the classification of the lag
+	Health ConsumerHealth `json:"health"`
+	// Reasons lists the thresholds that were exceeded
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// CalculateConsumerLag derives lag from Stream and Consumer state, now is used to determine the age of pending messages
+func CalculateConsumerLag(stream api.StreamState, consumer api.ConsumerState, now time.Time) *ConsumerLag {
+	lag := &ConsumerLag{
+		StreamLastSeq: stream.LastSeq,
+		Delivered:     consumer.Delivered,
+		AckFloor:      consumer.AckFloor,
+		AckPending:    len(consumer.Pending),
+		Redelivered:   len(consumer.Redelivered),
+		Health:        ConsumerHealthy,
+	}
+
+	if stream.LastSeq > consumer.Delivered.StreamSeq {
+		lag.Pending = stream.LastSeq - consumer.Delivered.StreamSeq
+	}
+
+	if consumer.Delivered.StreamSeq > consumer.AckFloor.StreamSeq {
+		lag.AckFloorGap = consumer.Delivered.StreamSeq - consumer.AckFloor.StreamSeq
+	}
+
+	for _, count := range consumer.Redelivered {
+		lag.Redeliveries += count
+	}
+
+	for _, ts := range consumer.Pending {
+		age := now.Sub(time.Unix(0, ts))
+		if age > lag.OldestPending {
+			lag.OldestPending = age
+		}
+	}
+
+	return lag
+}
+
+// Classify sets Health and Reasons based on t
+func (l *ConsumerLag) Classify(t ConsumerHealthThresholds) ConsumerHealth {
+	l.Health = ConsumerHealthy
+	l.Reasons = nil
+
+	check := func(critical bool, warning bool, format string, a ...interface{}) {
+		switch {
+		case critical:
+			l.Health = ConsumerCritical
+		case warning:
+			if l.Health == ConsumerHealthy {
+				l.Health = ConsumerWarning
+			}
+		default:
+			return
+		}
+
+		l.Reasons = append(l.Reasons, fmt.Sprintf(format, a...))
+	}
+
+	check(t.PendingCritical > 0 && l.Pending >= t.PendingCritical, t.PendingWarning > 0 && l.Pending >= t.PendingWarning, "%d messages pending delivery", l.Pending)
+	check(t.AckPendingCritical > 0 && l.AckPending >= t.AckPendingCritical, t.AckPendingWarning > 0 && l.AckPending >= t.AckPendingWarning, "%d messages pending acknowledgement", l.AckPending)
+	check(t.RedeliveredCritical > 0 && l.Redelivered >= t.RedeliveredCritical, t.RedeliveredWarning > 0 && l.Redelivered >= t.RedeliveredWarning, "%d messages redelivered", l.Redelivered)
+	check(t.OldestPendingCritical > 0 && l.OldestPending >= t.OldestPendingCritical, t.OldestPendingWarning > 0 && l.OldestPending >= t.OldestPendingWarning, "oldest pending message is %v old", l.OldestPending.Round(time.Second))
+
+	return l.Health
+}
+
+// Lag reports how far the Consumer is behind its Stream classified using DefaultConsumerHealthThresholds
+func (c *Consumer) Lag() (*ConsumerLag, error) {
+	return c.Health(DefaultConsumerHealthThresholds)
+}
+
+// Health reports how far the Consumer is behind its Stream classified using t
+func (c *Consumer) Health(t ConsumerHealthThresholds) (*ConsumerLag, error) {
+	sinfo, err := loadStreamInfo(c.stream, c.cfg.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	cstate, err := c.State()
+	if err != nil {
+		return nil, err
+	}
+
+	lag := CalculateConsumerLag(sinfo.State, cstate, time.Now())
+	lag.Stream = c.stream
+	lag.Consumer = c.name
+	lag.Classify(t)
+
+	return lag, nil
+}
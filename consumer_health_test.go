@@ -0,0 +1,99 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestConsumer_Lag(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("LAG"), jsm.DeliverAllAvailable())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 10; i++ {
+		_, err = stream.Publish("ORDERS.new", []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+	}
+
+	// 3 delivered, 1 acked
+	for i := 0; i < 3; i++ {
+		msg, err := consumer.NextJSMsg()
+		checkErr(t, err, "next failed")
+		if i == 0 {
+			checkErr(t, msg.AckSync(), "ack failed")
+		}
+	}
+
+	lag, err := consumer.Lag()
+	checkErr(t, err, "lag failed")
+
+	last := lag.StreamLastSeq
+	if lag.Stream != "ORDERS" || lag.Consumer != "LAG" || lag.Pending != 7 || lag.AckPending != 2 || lag.Delivered.StreamSeq != last-7 {
+		t.Fatalf("invalid lag %+v", lag)
+	}
+
+	if lag.Health != jsm.ConsumerHealthy {
+		t.Fatalf("expected a healthy consumer got %s: %v", lag.Health, lag.Reasons)
+	}
+
+	lag, err = consumer.Health(jsm.ConsumerHealthThresholds{PendingWarning: 5, AckPendingCritical: 2})
+	checkErr(t, err, "health failed")
+	if lag.Health != jsm.ConsumerCritical || len(lag.Reasons) != 2 {
+		t.Fatalf("expected a critical consumer got %s: %v", lag.Health, lag.Reasons)
+	}
+}
+
+func TestCalculateConsumerLag(t *testing.T) {
+	now := time.Now()
+
+	lag := jsm.CalculateConsumerLag(api.StreamState{LastSeq: 100}, api.ConsumerState{
+		Delivered:   api.SequencePair{StreamSeq: 80, ConsumerSeq: 85},
+		AckFloor:    api.SequencePair{StreamSeq: 70, ConsumerSeq: 72},
+		Pending:     map[uint64]int64{75: now.Add(-2 * time.Minute).UnixNano(), 79: now.Add(-time.Second).UnixNano()},
+		Redelivered: map[uint64]uint64{75: 3, 79: 1},
+	}, now)
+
+	if lag.Pending != 20 || lag.AckFloorGap != 10 || lag.AckPending != 2 || lag.Redelivered != 2 || lag.Redeliveries != 4 {
+		t.Fatalf("invalid lag %+v", lag)
+	}
+
+	if lag.OldestPending != 2*time.Minute {
+		t.Fatalf("expected 2 minutes oldest pending got %v", lag.OldestPending)
+	}
+
+	if lag.Classify(jsm.DefaultConsumerHealthThresholds) != jsm.ConsumerWarning || len(lag.Reasons) != 1 {
+		t.Fatalf("expected a warning got %s: %v", lag.Health, lag.Reasons)
+	}
+
+	if lag.Classify(jsm.ConsumerHealthThresholds{}) != jsm.ConsumerHealthy || len(lag.Reasons) != 0 {
+		t.Fatalf("expected healthy without thresholds got %s: %v", lag.Health, lag.Reasons)
+	}
+
+	// consumer ahead of a stale stream state
+	lag = jsm.CalculateConsumerLag(api.StreamState{LastSeq: 10}, api.ConsumerState{Delivered: api.SequencePair{StreamSeq: 12}}, now)
+	if lag.Pending != 0 {
+		t.Fatalf("expected no pending messages got %d", lag.Pending)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// consumer fields the server allows to be changed on an existing durable, only while nothing is subscribed to it
+var mutableConsumerFields = []string{"deliver_subject"}
+
+// ImmutableConsumerChanges are the fields that differ between current and desired that can not be updated in place
+func ImmutableConsumerChanges(current api.ConsumerConfig, desired api.ConsumerConfig) []string {
+	var immutable []string
+
+	for _, c := range diffConfigs(current, desired) {
+		if !contains(mutableConsumerFields, c.Field) {
+			immutable = append(immutable, c.Field)
+			continue
+		}
+
+		// the server only allows switching between push subjects
+		if c.Field == "deliver_subject" && (current.DeliverSubject == "" || desired.DeliverSubject == "") {
+			immutable = append(immutable, c.Field)
+		}
+	}
+
+	return immutable
+}
+
+// UpdateConfiguration updates a durable Consumer using cfg modified by opts, only the delivery subject of a push
+// based Consumer without active subscriptions can be changed, use RecreateWithConfiguration for other changes
+func (c *Consumer) UpdateConfiguration(cfg api.ConsumerConfig, opts ...ConsumerOption) error {
+	ncfg, err := c.updatedConfiguration(cfg, opts...)
+	if err != nil {
+		return err
+	}
+
+	changes := diffConfigs(c.Configuration(), ncfg.ConsumerConfig)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	immutable := ImmutableConsumerChanges(c.Configuration(), ncfg.ConsumerConfig)
+	if len(immutable) > 0 {
+		return fmt.Errorf("%s can not be changed, use RecreateWithConfiguration to recreate the consumer preserving its position", strings.Join(immutable, ", "))
+	}
+
+	_, err = createDurableConsumer(api.CreateConsumerRequest{Stream: c.stream, Config: ncfg.ConsumerConfig}, c.cfg.conn)
+	if err != nil {
+		return err
+	}
+
+	return c.Reset()
+}
+
+// RecreateWithConfiguration deletes the durable Consumer and creates it again using cfg modified by opts. The new
+// Consumer starts after the old ack floor so unacknowledged messages are delivered again, the delivery policy
+// in cfg is only used when the old Consumer never delivered any messages
+func (c *Consumer) RecreateWithConfiguration(cfg api.ConsumerConfig, opts ...ConsumerOption) error {
+	start, err := c.ackFloorStart()
+	if err != nil {
+		return err
+	}
+
+	ncfg, err := c.updatedConfiguration(cfg, append(opts, start...)...)
+	if err != nil {
+		return err
+	}
+
+	err = c.Delete()
+	if err != nil {
+		return err
+	}
+
+	_, err = createDurableConsumer(api.CreateConsumerRequest{Stream: c.stream, Config: ncfg.ConsumerConfig}, c.cfg.conn)
+	if err != nil {
+		return fmt.Errorf("consumer %s > %s was deleted but could not be recreated at stream sequence %d: %s", c.stream, c.name, ncfg.OptStartSeq, err)
+	}
+
+	return c.Reset()
+}
+
+func (c *Consumer) updatedConfiguration(cfg api.ConsumerConfig, opts ...ConsumerOption) (*ConsumerCfg, error) {
+	if !c.IsDurable() {
+		return nil, fmt.Errorf("only durable consumers can be updated")
+	}
+
+	ncfg, err := NewConsumerConfiguration(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ncfg.Durable != c.name {
+		return nil, fmt.Errorf("durable name can not be changed from %s to %s", c.name, ncfg.Durable)
+	}
+
+	valid, errs := ncfg.Validate()
+	if !valid {
+		return nil, validationError(errs)
+	}
+
+	return ncfg, nil
+}
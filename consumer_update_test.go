@@ -0,0 +1,110 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestConsumer_UpdateConfiguration(t *testing.T) {
+	srv, nc, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("PUSH"), jsm.DeliverySubject("out"))
+	checkErr(t, err, "create failed")
+
+	err = consumer.UpdateConfiguration(consumer.Configuration(), jsm.DeliverySubject("other"))
+	checkErr(t, err, "update failed")
+
+	if consumer.DeliverySubject() != "other" {
+		t.Fatalf("expected delivery subject other got %s", consumer.DeliverySubject())
+	}
+
+	err = consumer.UpdateConfiguration(consumer.Configuration(), jsm.AckWait(time.Minute), jsm.DeliverySubject(""))
+	if err == nil || !strings.Contains(err.Error(), "deliver_subject, ack_wait can not be changed") {
+		t.Fatalf("expected immutable fields to fail got %v", err)
+	}
+
+	err = consumer.UpdateConfiguration(consumer.Configuration(), jsm.DurableName("OTHER"))
+	if err == nil {
+		t.Fatalf("expected a durable name change to fail")
+	}
+
+	// unchanged is a noop
+	checkErr(t, consumer.UpdateConfiguration(consumer.Configuration()), "update failed")
+
+	changes := jsm.ImmutableConsumerChanges(api.ConsumerConfig{AckWait: time.Second, DeliverSubject: "x"}, api.ConsumerConfig{AckWait: time.Minute, DeliverSubject: "y"})
+	if len(changes) != 1 || changes[0] != "ack_wait" {
+		t.Fatalf("invalid changes %v", changes)
+	}
+}
+
+func TestConsumer_RecreateWithConfiguration(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("PULL"), jsm.DeliverAllAvailable())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 10; i++ {
+		_, err = stream.Publish("ORDERS.new", []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+	}
+
+	for i := 0; i < 4; i++ {
+		msg, err := consumer.NextJSMsg()
+		checkErr(t, err, "next failed")
+		checkErr(t, msg.AckSync(), "ack failed")
+	}
+
+	// the ack floor is updated lazily
+	var state api.ConsumerState
+	for i := 0; i < 20; i++ {
+		state, err = consumer.State()
+		checkErr(t, err, "state failed")
+		if state.AckFloor.StreamSeq == state.Delivered.StreamSeq {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	err = consumer.RecreateWithConfiguration(consumer.Configuration(), jsm.AckWait(time.Minute), jsm.MaxDeliveryAttempts(5))
+	checkErr(t, err, "recreate failed")
+
+	if consumer.AckWait() != time.Minute || consumer.MaxDeliver() != 5 {
+		t.Fatalf("configuration was not updated: %+v", consumer.Configuration())
+	}
+
+	if consumer.DeliverPolicy() != api.DeliverByStartSequence || consumer.StartSequence() != state.AckFloor.StreamSeq+1 {
+		t.Fatalf("expected to start after the ack floor %d got %+v", state.AckFloor.StreamSeq, consumer.Configuration())
+	}
+
+	msg, err := consumer.NextJSMsg()
+	checkErr(t, err, "next failed")
+	info, err := msg.Metadata()
+	checkErr(t, err, "metadata failed")
+	if info.StreamSequence() != int(state.AckFloor.StreamSeq+1) {
+		t.Fatalf("expected stream sequence %d got %d", state.AckFloor.StreamSeq+1, info.StreamSequence())
+	}
+}
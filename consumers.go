@@ -15,6 +15,7 @@ package jsm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -68,7 +69,7 @@ func NewConsumerFromDefault(stream string, dflt api.ConsumerConfig, opts ...Cons
 
 	valid, errs := cfg.Validate()
 	if !valid {
-		return nil, fmt.Errorf("configuration validation failed: %s", strings.Join(errs, ", "))
+		return nil, validationError(errs)
 	}
 
 	req := api.CreateConsumerRequest{
@@ -146,7 +147,7 @@ func LoadOrNewConsumerFromDefault(stream string, name string, template api.Consu
 	}
 
 	c, err := LoadConsumer(stream, name, cfg.ropts...)
-	if c == nil || err != nil {
+	if errors.Is(err, api.ErrConsumerNotFound) {
 		return NewConsumerFromDefault(stream, template, opts...)
 	}
 
@@ -394,7 +395,7 @@ func (c *Consumer) NextSubject() string {
 
 	s, _ := NextSubject(c.stream, c.name)
 
-	return s
+	return c.cfg.conn.apiSubject(s)
 }
 
 // NextSubject returns the subject used to retrieve the next message for pull-based Consumers, empty when not a pull-base consumer
@@ -416,17 +417,17 @@ func (c *Consumer) AckSampleSubject() string {
 		return ""
 	}
 
-	return api.JetStreamMetricConsumerAckPre + "." + c.StreamName() + "." + c.name
+	return c.cfg.conn.apiSubject(api.JetStreamMetricConsumerAckPre + "." + c.StreamName() + "." + c.name)
 }
 
 // AdvisorySubject is a wildcard subscription subject that subscribes to all advisories for this consumer
 func (c *Consumer) AdvisorySubject() string {
-	return api.JetStreamAdvisoryPrefix + "." + "*" + "." + c.StreamName() + "." + c.name
+	return c.cfg.conn.apiSubject(api.JetStreamAdvisoryPrefix + "." + "*" + "." + c.StreamName() + "." + c.name)
 }
 
 // MetricSubject is a wildcard subscription subject that subscribes to all metrics for this consumer
 func (c *Consumer) MetricSubject() string {
-	return api.JetStreamMetricPrefix + "." + "*" + "." + c.StreamName() + "." + c.name
+	return c.cfg.conn.apiSubject(api.JetStreamMetricPrefix + "." + "*" + "." + c.StreamName() + "." + c.name)
 }
 
 // Subscribe see nats.Subscribe
@@ -456,6 +457,13 @@ func (c *Consumer) ChanQueueSubscribe(group string, ch chan *nats.Msg) (sub *nat
 	return c.cfg.conn.nc.ChanQueueSubscribe(c.DeliverySubject(), group, ch)
 }
 
+// SubscribeJSMsg is like Subscribe but delivers messages wrapped in a Msg that can be acknowledged
+func (c *Consumer) SubscribeJSMsg(h func(*Msg)) (sub *nats.Subscription, err error) {
+	return c.Subscribe(func(m *nats.Msg) {
+		h(newMsg(m, c.cfg.conn))
+	})
+}
+
 // SubscribeSync see nats.SubscribeSync
 func (c *Consumer) SubscribeSync() (sub *nats.Subscription, err error) {
 	if !c.IsPushMode() {
@@ -510,7 +518,24 @@ func NextMsg(stream string, consumer string, opts ...RequestOption) (msgs *nats.
 
 // NextMsg retrieves the next message
 func (c *Consumer) NextMsg(opts ...RequestOption) (m *nats.Msg, err error) {
-	return NextMsg(c.stream, c.name, append(c.cfg.ropts, opts...)...)
+	return NextMsg(c.stream, c.name, append(append([]RequestOption{}, c.cfg.ropts...), opts...)...)
+}
+
+// NextJSMsg retrieves the next message wrapped in a Msg that can be acknowledged
+func (c *Consumer) NextJSMsg(opts ...RequestOption) (*Msg, error) {
+	opts = append(append([]RequestOption{}, c.cfg.ropts...), opts...)
+
+	ropts, err := newreqoptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NextMsg(c.stream, c.name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMsg(m, ropts), nil
 }
 
 // State returns the Consumer state
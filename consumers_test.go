@@ -14,6 +14,7 @@
 package jsm_test
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
@@ -201,6 +202,11 @@ func TestLoadConsumer(t *testing.T) {
 	if !consumer.IsSampled() {
 		t.Fatal("expected a sampled consumer")
 	}
+
+	_, err = jsm.LoadConsumer("ORDERS", "MISSING")
+	if !errors.Is(err, api.ErrConsumerNotFound) {
+		t.Fatalf("expected consumer not found got %v", err)
+	}
 }
 
 func TestLoadOrNewConsumer(t *testing.T) {
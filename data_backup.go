@@ -0,0 +1,417 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+const (
+	dataManifestFile = "manifest.json"
+	dataRestoreFileT = "restore_%s.json"
+)
+
+// StreamDataManifest describes a backup of the messages in a Stream made by BackupStreamData
+type StreamDataManifest struct {
+	Stream   string            `json:"stream"`
+	Config   api.StreamConfig  `json:"config"`
+	Created  time.Time         `json:"created"`
+	FirstSeq uint64            `json:"first_seq"`
+	LastSeq  uint64            `json:"last_seq"`
+	Chunks   []StreamDataChunk `json:"chunks"`
+	Complete bool              `json:"complete"`
+}
+
+// StreamDataChunk is a gzip compressed file holding a range of messages, one JSON encoded api.StoredMsg per line
+type StreamDataChunk struct {
+	File     string `json:"file"`
+	FirstSeq uint64 `json:"first_seq"`
+	LastSeq  uint64 `json:"last_seq"`
+	Messages uint64 `json:"messages"`
+	Checksum string `json:"checksum"`
+}
+
+// Messages is the total number of messages in all chunks
+func (m *StreamDataManifest) Messages() (count uint64) {
+	for _, c := range m.Chunks {
+		count += c.Messages
+	}
+
+	return count
+}
+
+type streamDataRestoreState struct {
+	Stream   string `json:"stream"`
+	LastSeq  uint64 `json:"last_seq"`
+	Messages uint64 `json:"messages"`
+}
+
+// LoadStreamDataManifest reads the manifest of a stream data backup made by BackupStreamData
+func LoadStreamDataManifest(backupDir string) (*StreamDataManifest, error) {
+	manifest := &StreamDataManifest{}
+	err := readJSONFile(filepath.Join(backupDir, dataManifestFile), manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// BackupStreamData creates a backup of all the messages in a Stream into backupDir.
+//
+// Messages are written in chunks and the backup can be resumed by calling BackupStreamData again with the same
+// backupDir after a failure, messages added to the Stream after the initial backup started are not included
+func BackupStreamData(stream string, backupDir string, opts ...BackupOption) error {
+	bopts := newBackupOptions(opts...)
+
+	str, err := LoadStream(stream, bopts.ropts...)
+	if err != nil {
+		return err
+	}
+
+	state, err := str.State()
+	if err != nil {
+		return err
+	}
+
+	manifest, err := LoadStreamDataManifest(backupDir)
+	switch {
+	case os.IsNotExist(err):
+		err = os.MkdirAll(backupDir, 0750)
+		if err != nil {
+			return err
+		}
+
+		manifest = &StreamDataManifest{
+			Stream:   stream,
+			Config:   str.Configuration(),
+			Created:  time.Now().UTC(),
+			FirstSeq: state.FirstSeq,
+			LastSeq:  state.LastSeq,
+			Chunks:   []StreamDataChunk{},
+		}
+
+		bopts.log.Infof("Creating backup of Stream %s messages %d to %d into %s", stream, state.FirstSeq, state.LastSeq, backupDir)
+
+	case err != nil:
+		return err
+
+	case manifest.Stream != stream:
+		return fmt.Errorf("%s holds a backup of Stream %s", backupDir, manifest.Stream)
+
+	case manifest.Complete:
+		return fmt.Errorf("backup of Stream %s in %s is already complete", stream, backupDir)
+
+	default:
+		bopts.log.Infof("Resuming backup of Stream %s into %s after %d chunks", stream, backupDir, len(manifest.Chunks))
+	}
+
+	next := manifest.FirstSeq
+	if len(manifest.Chunks) > 0 {
+		next = manifest.Chunks[len(manifest.Chunks)-1].LastSeq + 1
+	}
+
+	// messages removed by limits since the backup started can not be backed up
+	if state.FirstSeq > next {
+		next = state.FirstSeq
+	}
+
+	bopts.report("stream_data", stream, stream, BackupPhaseStarted, nil)
+
+	for manifest.LastSeq > 0 && next <= manifest.LastSeq {
+		chunk, err := backupStreamDataChunk(str, backupDir, len(manifest.Chunks)+1, next, manifest.LastSeq, bopts)
+		if err != nil {
+			bopts.reportResult("stream_data", stream, stream, err)
+			return err
+		}
+
+		manifest.Chunks = append(manifest.Chunks, *chunk)
+		err = writeJSONFile(filepath.Join(backupDir, dataManifestFile), manifest)
+		if err != nil {
+			bopts.reportResult("stream_data", stream, stream, err)
+			return err
+		}
+
+		next = chunk.LastSeq + 1
+
+		bopts.log.Debugf("Wrote chunk %s with %d messages", chunk.File, chunk.Messages)
+		bopts.emit(BackupEvent{Type: "stream_data", Stream: stream, Name: stream, Phase: BackupPhaseProgress, Sequence: chunk.LastSeq, Messages: manifest.Messages()})
+	}
+
+	manifest.Complete = true
+	err = writeJSONFile(filepath.Join(backupDir, dataManifestFile), manifest)
+	bopts.reportResult("stream_data", stream, stream, err)
+	if err != nil {
+		return err
+	}
+
+	bopts.log.Infof("Backup of %d messages from Stream %s complete", manifest.Messages(), stream)
+
+	return nil
+}
+
+func backupStreamDataChunk(stream *Stream, backupDir string, idx int, first uint64, last uint64, bopts *backupOptions) (*StreamDataChunk, error) {
+	chunk := &StreamDataChunk{
+		File:     fmt.Sprintf("chunk_%06d.json.gz", idx),
+		FirstSeq: first,
+	}
+
+	path := filepath.Join(backupDir, chunk.File)
+	tf, err := ioutil.TempFile(backupDir, chunk.File)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tf.Name())
+	defer tf.Close()
+
+	sum := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(tf, sum))
+	enc := json.NewEncoder(gz)
+
+	seq := first
+	for ; seq <= last && chunk.Messages < uint64(bopts.chunkSize); seq++ {
+		msg, err := stream.LoadMessage(int(seq))
+		if errors.Is(err, api.ErrMessageNotFound) {
+			bopts.log.Debugf("Skipping deleted message %d", seq)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not load message %d: %w", seq, err)
+		}
+
+		err = enc.Encode(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk.Messages++
+	}
+
+	chunk.LastSeq = seq - 1
+
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tf.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Rename(tf.Name(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk.Checksum = fmt.Sprintf("%x", sum.Sum(nil))
+
+	return chunk, nil
+}
+
+// RestoreStreamData publishes all the messages from a backup made by BackupStreamData into a Stream, when stream
+// is empty the name of the backed up Stream is used and the Stream is created using the backed up configuration
+// if it does not exist.
+//
+// The Stream has to listen on the subjects of the backed up messages. Restored messages get new timestamps as the
+// server assigns the time a message is received, the original times are only kept in the backup.
+//
+// Progress is recorded in backupDir so a failed restore can be resumed by calling RestoreStreamData again
+func RestoreStreamData(backupDir string, stream string, opts ...BackupOption) error {
+	bopts := newBackupOptions(opts...)
+
+	ropts, err := newreqoptions(bopts.ropts...)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := LoadStreamDataManifest(backupDir)
+	if err != nil {
+		return err
+	}
+
+	if !manifest.Complete {
+		return fmt.Errorf("backup of Stream %s in %s is not complete", manifest.Stream, backupDir)
+	}
+
+	if stream == "" {
+		stream = manifest.Stream
+	}
+
+	_, err = LoadStream(stream, bopts.ropts...)
+	if errors.Is(err, api.ErrStreamNotFound) {
+		bopts.log.Infof("Creating Stream %s", stream)
+		cfg := manifest.Config
+		cfg.Name = stream
+		_, err = NewStreamFromDefault(stream, cfg, StreamConnection(bopts.ropts...))
+	}
+	if err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(backupDir, fmt.Sprintf(dataRestoreFileT, stream))
+	state := &streamDataRestoreState{Stream: stream}
+	err = readJSONFile(statePath, state)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if state.LastSeq > 0 {
+		bopts.log.Infof("Resuming restore into Stream %s after message %d", stream, state.LastSeq)
+	}
+
+	bopts.report("stream_data", stream, stream, BackupPhaseStarted, nil)
+
+	for _, chunk := range manifest.Chunks {
+		if chunk.LastSeq <= state.LastSeq {
+			continue
+		}
+
+		err = restoreStreamDataChunk(backupDir, chunk, stream, state, ropts, bopts)
+
+		// saved even on failure so that the messages that were published are not published again
+		serr := writeJSONFile(statePath, state)
+		if err == nil {
+			err = serr
+		}
+		if err != nil {
+			bopts.reportResult("stream_data", stream, stream, err)
+			return err
+		}
+
+		bopts.emit(BackupEvent{Type: "stream_data", Stream: stream, Name: stream, Phase: BackupPhaseProgress, Sequence: state.LastSeq, Messages: state.Messages})
+	}
+
+	bopts.reportResult("stream_data", stream, stream, nil)
+	bopts.log.Infof("Restored %d messages into Stream %s", state.Messages, stream)
+
+	return nil
+}
+
+func restoreStreamDataChunk(backupDir string, chunk StreamDataChunk, stream string, state *streamDataRestoreState, ropts *reqoptions, bopts *backupOptions) error {
+	path := filepath.Join(backupDir, chunk.File)
+
+	bopts.log.Debugf("Restoring messages from %s", path)
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !verifySum(raw, chunk.Checksum) {
+		return fmt.Errorf("data checksum failed for %s", path)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		msg := api.StoredMsg{}
+		err = json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			return fmt.Errorf("invalid message in %s: %s", path, err)
+		}
+
+		if msg.Sequence <= state.LastSeq {
+			continue
+		}
+
+		ack, err := publishForRestore(msg, ropts)
+		if err != nil {
+			return fmt.Errorf("could not restore message %d: %w", msg.Sequence, err)
+		}
+
+		if ack.Stream != stream {
+			return fmt.Errorf("message %d on subject %s was stored in Stream %s", msg.Sequence, msg.Subject, ack.Stream)
+		}
+
+		state.LastSeq = msg.Sequence
+		state.Messages++
+	}
+
+	if scanner.Err() != nil {
+		return scanner.Err()
+	}
+
+	// the chunk might end with deleted messages
+	state.LastSeq = chunk.LastSeq
+
+	return nil
+}
+
+// publishForRestore publishes through the shared request path so observers see every message, publishes are not
+// idempotent so retry policies do not resend them
+func publishForRestore(msg api.StoredMsg, ropts *reqoptions) (*api.PubAck, error) {
+	res, err := request(msg.Subject, msg.Data, ropts)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePubAck(res)
+}
+
+func readJSONFile(path string, target interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
+
+// writes the file to a temporary file and then renames it so the original is never partially written
+func writeJSONFile(path string, data interface{}) error {
+	j, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tf, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tf.Name())
+
+	_, err = tf.Write(j)
+	if err != nil {
+		tf.Close()
+		return err
+	}
+
+	err = tf.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tf.Name(), path)
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestStreamDataBackupRestore(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	for i := 1; i <= 25; i++ {
+		_, err = nc.Request(fmt.Sprintf("ORDERS.%d", i), []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	err = stream.DeleteMessage(5)
+	checkErr(t, err, "delete failed")
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	dir := filepath.Join(td, "data")
+	err = jsm.BackupStreamData("ORDERS", dir, jsm.BackupChunkSize(10))
+	checkErr(t, err, "backup failed")
+
+	manifest, err := jsm.LoadStreamDataManifest(dir)
+	checkErr(t, err, "manifest failed")
+	if !manifest.Complete || len(manifest.Chunks) != 3 || manifest.Messages() != 24 {
+		t.Fatalf("invalid manifest: %+v", manifest)
+	}
+
+	if manifest.Chunks[0].FirstSeq != 1 || manifest.Chunks[0].LastSeq != 11 || manifest.Chunks[2].LastSeq != 25 {
+		t.Fatalf("invalid chunks: %+v", manifest.Chunks)
+	}
+
+	err = jsm.BackupStreamData("ORDERS", dir)
+	if err == nil {
+		t.Fatalf("expected a complete backup to fail")
+	}
+
+	// simulates a backup that failed after the first chunk
+	manifest.Complete = false
+	manifest.Chunks = manifest.Chunks[:1]
+	writeTestJSON(t, filepath.Join(dir, "manifest.json"), manifest)
+
+	var progress []uint64
+	err = jsm.BackupStreamData("ORDERS", dir, jsm.BackupChunkSize(10), jsm.BackupProgress(func(e jsm.BackupEvent) {
+		if e.Phase == jsm.BackupPhaseProgress {
+			progress = append(progress, e.Messages)
+		}
+	}))
+	checkErr(t, err, "resume failed")
+
+	if len(progress) != 2 || progress[1] != 24 {
+		t.Fatalf("expected 2 more chunks got %v", progress)
+	}
+
+	err = stream.Delete()
+	checkErr(t, err, "delete failed")
+
+	// restored messages are published through the shared request path
+	published := 0
+	observer := func(ctx context.Context, subject string, _ []byte) (context.Context, func(jsm.RequestTrace)) {
+		return ctx, func(trace jsm.RequestTrace) {
+			if strings.HasPrefix(subject, "ORDERS.") && trace.Error == nil {
+				published++
+			}
+		}
+	}
+
+	err = jsm.RestoreStreamData(dir, "", jsm.BackupConnection(jsm.WithRequestObserver(observer)))
+	checkErr(t, err, "restore failed")
+
+	if published != 24 {
+		t.Fatalf("expected 24 observed publishes got %d", published)
+	}
+
+	stream, err = jsm.LoadStream("ORDERS")
+	checkErr(t, err, "load failed")
+
+	state, err := stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 24 {
+		t.Fatalf("expected 24 messages got %d", state.Msgs)
+	}
+
+	msg, err := stream.LoadMessage(5)
+	checkErr(t, err, "load message failed")
+	if msg.Subject != "ORDERS.6" || string(msg.Data) != "order 6" {
+		t.Fatalf("invalid message %+v", msg)
+	}
+
+	// a finished restore does not publish again
+	err = jsm.RestoreStreamData(dir, "")
+	checkErr(t, err, "restore failed")
+
+	state, err = stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 24 {
+		t.Fatalf("expected 24 messages got %d", state.Msgs)
+	}
+}
+
+func TestStreamDataRestoreResume(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	for i := 1; i <= 20; i++ {
+		_, err = nc.Request(fmt.Sprintf("ORDERS.%d", i), []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	dir := filepath.Join(td, "data")
+	err = jsm.BackupStreamData("ORDERS", dir, jsm.BackupChunkSize(5))
+	checkErr(t, err, "backup failed")
+
+	// simulates a restore that failed after the first 12 messages
+	writeTestJSON(t, filepath.Join(dir, "restore_ORDERS.json"), map[string]interface{}{"stream": "ORDERS", "last_seq": 12, "messages": 12})
+
+	orders, err := jsm.LoadStream("ORDERS")
+	checkErr(t, err, "load failed")
+	err = orders.Purge()
+	checkErr(t, err, "purge failed")
+
+	err = jsm.RestoreStreamData(dir, "ORDERS")
+	checkErr(t, err, "restore failed")
+
+	state, err := orders.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 8 {
+		t.Fatalf("expected 8 messages got %d", state.Msgs)
+	}
+
+	// the ORDERS.* subjects are not stored in ARCHIVE
+	_, err = jsm.NewStream("ARCHIVE", jsm.Subjects("ARCHIVE.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	err = jsm.RestoreStreamData(dir, "ARCHIVE")
+	if err == nil {
+		t.Fatalf("expected restore into ARCHIVE to fail")
+	}
+}
+
+func writeTestJSON(t *testing.T, path string, data interface{}) {
+	t.Helper()
+
+	j, err := json.Marshal(data)
+	checkErr(t, err, "marshal failed")
+
+	err = ioutil.WriteFile(path, j, 0600)
+	checkErr(t, err, "write failed")
+}
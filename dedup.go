@@ -0,0 +1,145 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// DedupCache remembers the IDs of messages that were published and acknowledged, publishing a message with an ID
+// seen within the window returns the original acknowledgement marked as a duplicate without publishing it again.
+//
+// The NATS client in use does not support headers so message IDs can not be sent to the server, this cache is
+// the client side fallback. It only knows about acknowledged messages, a publish that timed out might still have
+// been stored by the server
+type DedupCache struct {
+	window time.Duration
+	mu     sync.Mutex
+	seen   map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	ack  api.PubAck
+	seen time.Time
+	// pending is closed once the publish that reserved the ID completed, nil once acknowledged
+	pending chan struct{}
+}
+
+// DefaultDedupWindow is the window used by NewDedupCache when none is given
+const DefaultDedupWindow = 2 * time.Minute
+
+// NewDedupCache creates a cache that considers messages with the same ID published within window duplicates,
+// DefaultDedupWindow is used when window is 0 or less
+func NewDedupCache(window time.Duration) *DedupCache {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+
+	return &DedupCache{window: window, seen: make(map[string]*dedupEntry)}
+}
+
+// NewDedupCache creates a DedupCache using the Stream DuplicateWindow, DefaultDedupWindow is used when it has none
+func (s *Stream) NewDedupCache() *DedupCache {
+	return NewDedupCache(s.DuplicateWindow())
+}
+
+// WithMsgID sets the ID of a published message used to detect duplicates, requires WithDedupCache
+func WithMsgID(id string) RequestOption {
+	return func(o *reqoptions) {
+		o.msgID = id
+	}
+}
+
+// WithDedupCache sets the cache used to detect duplicate messages published with WithMsgID
+func WithDedupCache(c *DedupCache) RequestOption {
+	return func(o *reqoptions) {
+		o.dedup = c
+	}
+}
+
+// Window is the duration IDs are remembered for
+func (c *DedupCache) Window() time.Duration {
+	return c.window
+}
+
+// Size is the number of IDs currently remembered, including those of messages being published
+func (c *DedupCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(time.Now())
+
+	return len(c.seen)
+}
+
+// reserve finds the acknowledgement of a previous message with the same id, marked as a duplicate. When there is
+// none the id is reserved and the caller has to publish and then call complete, concurrent callers with the same id
+// wait for that publish to complete
+func (c *DedupCache) reserve(id string) (*api.PubAck, bool) {
+	for {
+		c.mu.Lock()
+		c.expire(time.Now())
+
+		entry, ok := c.seen[id]
+		if !ok {
+			c.seen[id] = &dedupEntry{pending: make(chan struct{})}
+			c.mu.Unlock()
+			return nil, false
+		}
+
+		if entry.pending != nil {
+			pending := entry.pending
+			c.mu.Unlock()
+			<-pending
+			continue
+		}
+
+		ack := entry.ack
+		c.mu.Unlock()
+
+		ack.Duplicate = true
+
+		return &ack, true
+	}
+}
+
+// complete finishes a publish of id reserved using reserve, a nil ack releases the id so it can be published again
+func (c *DedupCache) complete(id string, ack *api.PubAck) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.seen[id]
+	if !ok || entry.pending == nil {
+		return
+	}
+
+	if ack == nil {
+		delete(c.seen, id)
+	} else {
+		c.seen[id] = &dedupEntry{ack: *ack, seen: time.Now()}
+	}
+
+	close(entry.pending)
+}
+
+func (c *DedupCache) expire(now time.Time) {
+	for id, entry := range c.seen {
+		if entry.pending == nil && now.Sub(entry.seen) > c.window {
+			delete(c.seen, id)
+		}
+	}
+}
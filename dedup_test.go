@@ -0,0 +1,188 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestPublish_Deduplication(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	cache := jsm.NewDedupCache(250 * time.Millisecond)
+	if cache.Window() != 250*time.Millisecond {
+		t.Fatalf("unexpected window %v", cache.Window())
+	}
+
+	_, err = stream.Publish("ORDERS.new", []byte("1"), jsm.WithMsgID("1"))
+	if err == nil {
+		t.Fatalf("expected a message ID without a cache to fail")
+	}
+
+	ack, err := stream.Publish("ORDERS.new", []byte("1"), jsm.WithMsgID("1"), jsm.WithDedupCache(cache))
+	checkErr(t, err, "publish failed")
+	if ack.Duplicate || ack.Sequence != 1 {
+		t.Fatalf("invalid ack %+v", ack)
+	}
+
+	ack, err = stream.Publish("ORDERS.new", []byte("1"), jsm.WithMsgID("1"), jsm.WithDedupCache(cache))
+	checkErr(t, err, "publish failed")
+	if !ack.Duplicate || ack.Sequence != 1 {
+		t.Fatalf("expected a duplicate of sequence 1 got %+v", ack)
+	}
+
+	ack, err = stream.Publish("ORDERS.new", []byte("2"), jsm.WithMsgID("2"), jsm.WithDedupCache(cache))
+	checkErr(t, err, "publish failed")
+	if ack.Duplicate || ack.Sequence != 2 {
+		t.Fatalf("invalid ack %+v", ack)
+	}
+
+	if cache.Size() != 2 {
+		t.Fatalf("expected 2 cached IDs got %d", cache.Size())
+	}
+
+	// outside the window its stored again
+	time.Sleep(300 * time.Millisecond)
+
+	ack, err = stream.Publish("ORDERS.new", []byte("1"), jsm.WithMsgID("1"), jsm.WithDedupCache(cache))
+	checkErr(t, err, "publish failed")
+	if ack.Duplicate || ack.Sequence != 3 {
+		t.Fatalf("invalid ack %+v", ack)
+	}
+
+	state, err := stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 3 {
+		t.Fatalf("expected 3 messages got %d", state.Msgs)
+	}
+}
+
+func TestPublish_ConcurrentDeduplication(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	cache := jsm.NewDedupCache(time.Minute)
+	wg := sync.WaitGroup{}
+	var stored, duplicates int32
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ack, err := stream.Publish("ORDERS.new", []byte("1"), jsm.WithMsgID("1"), jsm.WithDedupCache(cache))
+			if err != nil {
+				t.Errorf("publish failed: %s", err)
+				return
+			}
+
+			if ack.Sequence != 1 {
+				t.Errorf("expected sequence 1 got %d", ack.Sequence)
+			}
+
+			if ack.Duplicate {
+				atomic.AddInt32(&duplicates, 1)
+			} else {
+				atomic.AddInt32(&stored, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if stored != 1 || duplicates != 19 {
+		t.Fatalf("expected 1 stored and 19 duplicates got %d and %d", stored, duplicates)
+	}
+
+	state, err := stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 1 {
+		t.Fatalf("expected 1 message got %d", state.Msgs)
+	}
+
+	// a failed publish releases the ID, nothing acknowledges messages on subjects without a stream
+	_, err = jsm.Publish("OTHER.new", []byte("2"), jsm.WithMsgID("2"), jsm.WithDedupCache(cache), jsm.WithTimeout(100*time.Millisecond))
+	if err == nil {
+		t.Fatalf("expected the publish to fail")
+	}
+
+	if cache.Size() != 1 {
+		t.Fatalf("expected only the stored ID to be cached got %d", cache.Size())
+	}
+}
+
+func TestNewDedupCache_DefaultWindow(t *testing.T) {
+	for _, w := range []time.Duration{0, -1} {
+		cache := jsm.NewDedupCache(w)
+		if cache.Window() != jsm.DefaultDedupWindow {
+			t.Fatalf("expected the default window for %v got %v", w, cache.Window())
+		}
+	}
+}
+
+func TestStream_DuplicateWindow(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.DuplicateWindow(time.Hour))
+	checkErr(t, err, "create failed")
+
+	cache := stream.NewDedupCache()
+	if stream.DuplicateWindow() != time.Hour || cache.Window() != time.Hour {
+		t.Fatalf("expected a 1 hour window got %v and %v", stream.DuplicateWindow(), cache.Window())
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err = stream.Publish("ORDERS.new", []byte("1"), jsm.WithMsgID("1"), jsm.WithDedupCache(cache))
+		checkErr(t, err, "publish failed")
+	}
+
+	state, err := stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 1 {
+		t.Fatalf("expected 1 message got %d", state.Msgs)
+	}
+
+	// the window is not sent to the server so loaded configurations match what was requested
+	loaded, err := jsm.LoadOrNewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.DuplicateWindow(time.Minute))
+	checkErr(t, err, "load failed")
+	if loaded.DuplicateWindow() != time.Minute {
+		t.Fatalf("expected a 1 minute window got %v", loaded.DuplicateWindow())
+	}
+
+	if diff := jsm.DiffStreamConfig(stream.Configuration(), loaded.Configuration()); len(diff) != 0 {
+		t.Fatalf("unexpected changes %v", diff)
+	}
+
+	unset, err := jsm.LoadStream("ORDERS")
+	checkErr(t, err, "load failed")
+	if unset.NewDedupCache().Window() != jsm.DefaultDedupWindow {
+		t.Fatalf("expected the default window")
+	}
+}
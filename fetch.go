@@ -0,0 +1,140 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// FetchBatch requests up to n messages from a pull based Consumer in a single request and waits for them until the
+// batch is complete or the timeout or context expires. The messages received are always returned, when the batch did
+// not complete the error that ended it is returned with them, context.DeadlineExceeded when the batch expired.
+//
+// Messages delivered after the batch expired are lost and will be redelivered once their AckWait passed
+func FetchBatch(stream string, consumer string, n int, opts ...RequestOption) ([]*Msg, error) {
+	msgs, errs, err := fetchBatch(stream, consumer, n, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := []*Msg{}
+	for m := range msgs {
+		batch = append(batch, m)
+	}
+
+	return batch, <-errs
+}
+
+// FetchBatchChan is like FetchBatch but delivers messages to the returned channel as they arrive, the channel is
+// closed once the batch is complete or expired. When the batch did not complete the error that ended it is sent to
+// the error channel before the message channel is closed, the error channel is closed once the batch is done
+func FetchBatchChan(stream string, consumer string, n int, opts ...RequestOption) (<-chan *Msg, <-chan error, error) {
+	return fetchBatch(stream, consumer, n, opts...)
+}
+
+// FetchBatch requests up to n messages in a single request, see FetchBatch
+func (c *Consumer) FetchBatch(n int, opts ...RequestOption) ([]*Msg, error) {
+	return FetchBatch(c.stream, c.name, n, append(append([]RequestOption{}, c.cfg.ropts...), opts...)...)
+}
+
+// FetchBatchChan requests up to n messages in a single request delivering them to the returned channel, see FetchBatchChan
+func (c *Consumer) FetchBatchChan(n int, opts ...RequestOption) (<-chan *Msg, <-chan error, error) {
+	return FetchBatchChan(c.stream, c.name, n, append(append([]RequestOption{}, c.cfg.ropts...), opts...)...)
+}
+
+func fetchBatch(stream string, consumer string, n int, opts ...RequestOption) (chan *Msg, chan error, error) {
+	if n < 1 {
+		return nil, nil, fmt.Errorf("batch size has to be at least 1")
+	}
+
+	ropts, err := newreqoptions(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subj, err := NextSubject(stream, consumer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := []byte(strconv.Itoa(n))
+
+	// the request has many responses so it can not go through request(), observers are notified once the batch is done
+	bopts, done := ropts, func(*nats.Msg, error) {}
+	if len(ropts.observers) > 0 {
+		bopts, done = ropts.startTrace(subj, req)
+	}
+
+	ctx, cancel := bopts.context()
+
+	inbox := nats.NewInbox()
+	received := make(chan *nats.Msg, n)
+	sub, err := ropts.nc.ChanSubscribe(inbox, received)
+	if err != nil {
+		cancel()
+		done(nil, err)
+		return nil, nil, err
+	}
+
+	err = sub.AutoUnsubscribe(n)
+	if err == nil {
+		err = ropts.nc.PublishRequest(ropts.apiSubject(subj), inbox, req)
+	}
+	if err != nil {
+		sub.Unsubscribe()
+		cancel()
+		done(nil, err)
+		return nil, nil, err
+	}
+
+	msgs := make(chan *Msg, n)
+	errs := make(chan error, 1)
+
+	go func() {
+		var last *nats.Msg
+		var err error
+
+		defer cancel()
+		defer sub.Unsubscribe()
+		defer func() { done(last, err) }()
+		defer close(errs)
+		defer close(msgs)
+
+		for count := 0; count < n; {
+			select {
+			case m := <-received:
+				last = m
+
+				err = ParseErrorResponse(m)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				msgs <- newMsg(m, ropts)
+				count++
+
+			case <-ctx.Done():
+				err = ctx.Err()
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	return msgs, errs, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestConsumer_FetchBatch(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("BATCH"), jsm.DeliverAllAvailable())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 15; i++ {
+		_, err = nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	msgs, err := consumer.FetchBatch(10, jsm.WithTimeout(time.Second))
+	checkErr(t, err, "fetch failed")
+
+	if len(msgs) != 10 {
+		t.Fatalf("expected 10 messages got %d", len(msgs))
+	}
+
+	for i, m := range msgs {
+		if string(m.Data) != fmt.Sprintf("%d", i) {
+			t.Fatalf("expected message %d got %q", i, m.Data)
+		}
+
+		info, err := m.Metadata()
+		checkErr(t, err, "metadata failed")
+		if info.Stream() != "ORDERS" || info.Consumer() != "BATCH" || info.ConsumerSequence() != i+1 {
+			t.Fatalf("invalid metadata %+v", info)
+		}
+
+		checkErr(t, m.Ack(), "ack failed")
+	}
+
+	// only 5 remain so the batch expires with what was received
+	start := time.Now()
+	msgs, err = consumer.FetchBatch(10, jsm.WithTimeout(250*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the partial batch to expire got %v", err)
+	}
+
+	if len(msgs) != 5 {
+		t.Fatalf("expected 5 messages got %d", len(msgs))
+	}
+
+	if time.Since(start) < 250*time.Millisecond {
+		t.Fatalf("expected the batch to wait for the timeout")
+	}
+
+	for _, m := range msgs {
+		checkErr(t, m.Ack(), "ack failed")
+	}
+
+	msgs, err = consumer.FetchBatch(10, jsm.WithTimeout(100*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) || len(msgs) != 0 {
+		t.Fatalf("expected a timeout without messages got %v and %d messages", err, len(msgs))
+	}
+}
+
+func TestConsumer_FetchBatchChan(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("BATCH"), jsm.DeliverAllAvailable())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 5; i++ {
+		_, err = nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	msgs, errs, err := consumer.FetchBatchChan(5, jsm.WithTimeout(time.Second))
+	checkErr(t, err, "fetch failed")
+
+	count := 0
+	for m := range msgs {
+		info, err := m.Metadata()
+		checkErr(t, err, "metadata failed")
+		if info.StreamSequence() != count+2 {
+			t.Fatalf("unexpected stream sequence %d", info.StreamSequence())
+		}
+
+		count++
+	}
+
+	if count != 5 {
+		t.Fatalf("expected 5 messages got %d", count)
+	}
+
+	checkErr(t, <-errs, "batch failed")
+
+	msgs, errs, err = consumer.FetchBatchChan(5, jsm.WithTimeout(100*time.Millisecond))
+	checkErr(t, err, "fetch failed")
+
+	for range msgs {
+		t.Fatalf("expected no messages")
+	}
+
+	if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the batch to expire got %v", err)
+	}
+}
+
+func TestFetchBatch_Observer(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("BATCH"), jsm.DeliverAllAvailable())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 2; i++ {
+		_, err = nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	rec := &traceRecorder{}
+	msgs, err := consumer.FetchBatch(2, jsm.WithTimeout(time.Second), jsm.WithRequestObserver(rec.observe))
+	checkErr(t, err, "fetch failed")
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages got %d", len(msgs))
+	}
+
+	rec.Lock()
+	defer rec.Unlock()
+
+	if len(rec.traces) != 1 {
+		t.Fatalf("expected 1 trace got %d", len(rec.traces))
+	}
+
+	trace := rec.traces[0]
+	if trace.Subject != "$JS.STREAM.ORDERS.CONSUMER.BATCH.NEXT" || trace.RequestSize != 1 || trace.Response == nil || trace.Error != nil {
+		t.Fatalf("invalid trace: %+v", trace)
+	}
+}
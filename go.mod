@@ -3,7 +3,9 @@ module github.com/nats-io/jsm.go
 go 1.14
 
 require (
+	github.com/ghodss/yaml v1.0.0
 	github.com/nats-io/nats-server/v2 v2.1.7-0.20200420182537-915876db61ea
 	github.com/nats-io/nats.go v1.9.2
 	github.com/xeipuuv/gojsonschema v1.2.0
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
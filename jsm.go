@@ -17,7 +17,7 @@ package jsm
 //go:generate go run api/gen.go
 
 import (
-	"context"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -84,13 +84,13 @@ func IsErrorResponse(m *nats.Msg) bool {
 	return strings.HasPrefix(string(m.Data), api.ErrPrefix)
 }
 
-// ParseErrorResponse parses the JetStream response, if it's an error returns an error instance holding the message else nil
+// ParseErrorResponse parses the JetStream response, if it's an error returns an *api.APIError holding the message else nil
 func ParseErrorResponse(m *nats.Msg) error {
 	if !IsErrorResponse(m) {
 		return nil
 	}
 
-	return fmt.Errorf(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(string(m.Data), api.ErrPrefix), " '"), "'"))
+	return api.NewAPIError(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(string(m.Data), api.ErrPrefix), " '"), "'"))
 }
 
 // IsOKResponse checks if the message holds a standard JetStream error
@@ -98,6 +98,26 @@ func IsOKResponse(m *nats.Msg) bool {
 	return strings.HasPrefix(string(m.Data), api.OK)
 }
 
+// parsePubAck parses the acknowledgement received after publishing into a Stream
+func parsePubAck(m *nats.Msg) (*api.PubAck, error) {
+	err := ParseErrorResponse(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsOKResponse(m) {
+		return nil, fmt.Errorf("invalid publish acknowledgement received: %q", m.Data)
+	}
+
+	ack := &api.PubAck{}
+	err = json.Unmarshal(bytes.TrimSpace(bytes.TrimPrefix(m.Data, []byte(api.OK))), ack)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publish acknowledgement received: %s", err)
+	}
+
+	return ack, nil
+}
+
 // IsKnownStream determines if a Stream is known
 func IsKnownStream(stream string, opts ...RequestOption) (bool, error) {
 	streams, err := StreamNames(opts...)
@@ -240,7 +260,7 @@ func ConsumerNames(stream string, opts ...RequestOption) (consumers []string, er
 
 // EachStream iterates over all known Streams
 func EachStream(cb func(*Stream), opts ...RequestOption) (err error) {
-	names, err := StreamNames()
+	names, err := StreamNames(opts...)
 	if err != nil {
 		return err
 	}
@@ -302,20 +322,35 @@ func request(subj string, data []byte, opts *reqoptions) (res *nats.Msg, err err
 		return nil, fmt.Errorf("nats connection is not set")
 	}
 
-	var ctx context.Context
-	var cancel func()
+	if len(opts.observers) > 0 {
+		var done func(*nats.Msg, error)
+		opts, done = opts.startTrace(subj, data)
+		defer func() { done(res, err) }()
+	}
 
-	if opts.ctx == nil {
-		ctx, cancel = context.WithTimeout(context.Background(), opts.timeout)
-		defer cancel()
-	} else {
-		ctx = opts.ctx
+	if opts.retry != nil && isIdempotentRequest(subj) {
+		return requestWithRetry(subj, data, opts)
 	}
 
-	res, err = opts.nc.RequestWithContext(ctx, subj, data)
+	return requestOnce(subj, data, opts)
+}
+
+func requestOnce(subj string, data []byte, opts *reqoptions) (res *nats.Msg, err error) {
+	ctx, cancel := opts.context()
+	defer cancel()
+
+	res, err = opts.nc.RequestWithContext(ctx, opts.apiSubject(subj), data)
 	if err != nil {
 		return nil, err
 	}
 
 	return res, ParseErrorResponse(res)
 }
+
+// validationError creates an api.APIError for client side configuration validation failures
+func validationError(errs []string) error {
+	return &api.APIError{
+		Code:        api.ErrCodeInvalidConfiguration,
+		Description: fmt.Sprintf("configuration validation failed: %s", strings.Join(errs, ", ")),
+	}
+}
@@ -15,6 +15,7 @@ package jsm_test
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -29,6 +30,16 @@ import (
 func startJSServer(t *testing.T) (*natsd.Server, *nats.Conn) {
 	t.Helper()
 
+	s, nc := startIsolatedJSServer(t)
+	jsm.SetConnection(nc)
+
+	return s, nc
+}
+
+// starts a server and connects to it without setting the package level connection
+func startIsolatedJSServer(t *testing.T) (*natsd.Server, *nats.Conn) {
+	t.Helper()
+
 	d, err := ioutil.TempDir("", "jstest")
 	if err != nil {
 		t.Fatalf("temp dir could not be made: %s", err)
@@ -57,8 +68,6 @@ func startJSServer(t *testing.T) (*natsd.Server, *nats.Conn) {
 		t.Fatalf("client start failed: %s", err)
 	}
 
-	jsm.SetConnection(nc)
-
 	return s, nc
 }
 
@@ -101,6 +110,16 @@ func TestParseErrorResponse(t *testing.T) {
 	if err.Error() != "test error" {
 		t.Fatalf("expected 'test error' got '%v'", err)
 	}
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an api.APIError got %T", err)
+	}
+
+	err = jsm.ParseErrorResponse(&nats.Msg{Data: []byte("-ERR 'stream not found'")})
+	if !errors.Is(err, api.ErrStreamNotFound) {
+		t.Fatalf("expected stream not found got %v", err)
+	}
 }
 
 func TestIsOKResponse(t *testing.T) {
@@ -0,0 +1,162 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// DefaultLastMessageWindow is how many of the most recent messages are searched when the server can not look up
+// messages by subject
+const DefaultLastMessageWindow = 1000
+
+// LastMessageOption configures a last message lookup
+type LastMessageOption func(o *lastMessageOptions)
+
+type lastMessageOptions struct {
+	window uint64
+}
+
+// LastMessageWindow sets how many of the most recent messages are searched when the server can not look up messages
+// by subject, defaults to DefaultLastMessageWindow
+func LastMessageWindow(w uint64) LastMessageOption {
+	return func(o *lastMessageOptions) {
+		if w > 0 {
+			o.window = w
+		}
+	}
+}
+
+// LoadLastMessageForSubject loads the most recent message stored for subject, wildcards are not supported.
+// api.ErrMessageNotFound is returned when there is no such message, on servers that can not look up messages by
+// subject this includes messages older than the search window
+func (s *Stream) LoadLastMessageForSubject(subject string, opts ...LastMessageOption) (api.StoredMsg, error) {
+	msgs, err := s.LoadLastMessagesForSubjects([]string{subject}, opts...)
+	if err != nil {
+		return api.StoredMsg{}, err
+	}
+
+	msg, ok := msgs[subject]
+	if !ok {
+		return api.StoredMsg{}, api.ErrMessageNotFound
+	}
+
+	return msg, nil
+}
+
+// LoadLastMessagesForSubjects loads the most recent message stored for each of subjects, subjects without messages
+// are not included in the result, see LoadLastMessageForSubject
+func (s *Stream) LoadLastMessagesForSubjects(subjects []string, opts ...LastMessageOption) (map[string]api.StoredMsg, error) {
+	lopts := &lastMessageOptions{window: DefaultLastMessageWindow}
+	for _, opt := range opts {
+		opt(lopts)
+	}
+
+	for _, subj := range subjects {
+		if subj == "" || strings.ContainsAny(subj, "*> \t") {
+			return nil, fmt.Errorf("%q is not a valid literal subject", subj)
+		}
+	}
+
+	found := make(map[string]api.StoredMsg)
+
+	for _, subj := range subjects {
+		msg, err := s.loadLastMessageBySubject(subj)
+		switch {
+		case errors.Is(err, api.ErrBadRequest):
+			return s.scanLastMessages(subjects, lopts.window)
+
+		case errors.Is(err, api.ErrMessageNotFound):
+			continue
+
+		case err != nil:
+			return nil, err
+		}
+
+		found[subj] = msg
+	}
+
+	return found, nil
+}
+
+func (s *Stream) loadLastMessageBySubject(subject string) (api.StoredMsg, error) {
+	req, err := json.Marshal(api.StreamMsgGetRequest{LastBySubject: subject})
+	if err != nil {
+		return api.StoredMsg{}, err
+	}
+
+	response, err := request(fmt.Sprintf(api.JetStreamMsgBySeqT, s.Name()), req, s.cfg.conn)
+	if err != nil {
+		return api.StoredMsg{}, err
+	}
+
+	msg := api.StoredMsg{}
+	err = json.Unmarshal(response.Data, &msg)
+	if err != nil {
+		return api.StoredMsg{}, err
+	}
+
+	return msg, nil
+}
+
+// scanLastMessages loads messages from the end of the Stream backwards until all subjects are found or window
+// sequences were searched
+func (s *Stream) scanLastMessages(subjects []string, window uint64) (map[string]api.StoredMsg, error) {
+	found := make(map[string]api.StoredMsg)
+
+	state, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Msgs == 0 {
+		return found, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, subj := range subjects {
+		wanted[subj] = true
+	}
+
+	first := state.FirstSeq
+	if state.LastSeq-first >= window {
+		first = state.LastSeq - window + 1
+	}
+
+	for seq := state.LastSeq; seq >= first && len(found) < len(wanted); seq-- {
+		msg, err := s.LoadMessage(int(seq))
+		// deleted messages leave gaps in the sequences
+		if errors.Is(err, api.ErrMessageNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !wanted[msg.Subject] {
+			continue
+		}
+
+		if _, ok := found[msg.Subject]; !ok {
+			found[msg.Subject] = msg
+		}
+	}
+
+	return found, nil
+}
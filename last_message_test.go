@@ -0,0 +1,67 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestStream_LoadLastMessageForSubject(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("DEVICES", jsm.Subjects("DEVICES.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.LoadLastMessageForSubject("DEVICES.a")
+	if !errors.Is(err, api.ErrMessageNotFound) {
+		t.Fatalf("expected not found on an empty stream, got %v", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		_, err = stream.Publish(fmt.Sprintf("DEVICES.%c", 'a'+i%3), []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+	}
+
+	// a: 3,6,9 b: 1,4,7,10 c: 2,5,8
+	checkErr(t, stream.DeleteMessage(8), "delete failed")
+
+	msg, err := stream.LoadLastMessageForSubject("DEVICES.b")
+	checkErr(t, err, "load failed")
+	if msg.Sequence != 10 || string(msg.Data) != "10" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	msgs, err := stream.LoadLastMessagesForSubjects([]string{"DEVICES.a", "DEVICES.c", "DEVICES.x"})
+	checkErr(t, err, "load failed")
+	if len(msgs) != 2 || msgs["DEVICES.a"].Sequence != 9 || msgs["DEVICES.c"].Sequence != 5 {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+
+	_, err = stream.LoadLastMessageForSubject("DEVICES.c", jsm.LastMessageWindow(3))
+	if !errors.Is(err, api.ErrMessageNotFound) {
+		t.Fatalf("expected not found outside the window, got %v", err)
+	}
+
+	_, err = stream.LoadLastMessageForSubject("DEVICES.*")
+	if err == nil {
+		t.Fatalf("expected wildcard subjects to fail")
+	}
+}
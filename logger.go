@@ -0,0 +1,65 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"log"
+)
+
+// Logger is a printf style logger used to report on long running operations like backups
+type Logger interface {
+	Debugf(format string, a ...interface{})
+	Infof(format string, a ...interface{})
+	Warnf(format string, a ...interface{})
+	Errorf(format string, a ...interface{})
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Debugf(string, ...interface{}) {}
+func (discardLogger) Infof(string, ...interface{})  {}
+func (discardLogger) Warnf(string, ...interface{})  {}
+func (discardLogger) Errorf(string, ...interface{}) {}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger creates a Logger that logs info, warning and error messages using l, when l is nil the standard logger is used
+func NewStdLogger(l *log.Logger) Logger {
+	return &stdLogger{l: l}
+}
+
+func (s *stdLogger) printf(format string, a ...interface{}) {
+	if s.l == nil {
+		log.Printf(format, a...)
+		return
+	}
+
+	s.l.Printf(format, a...)
+}
+
+func (s *stdLogger) Debugf(string, ...interface{}) {}
+
+func (s *stdLogger) Infof(format string, a ...interface{}) {
+	s.printf(format, a...)
+}
+
+func (s *stdLogger) Warnf(format string, a ...interface{}) {
+	s.printf("WARN: "+format, a...)
+}
+
+func (s *stdLogger) Errorf(format string, a ...interface{}) {
+	s.printf("ERROR: "+format, a...)
+}
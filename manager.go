@@ -0,0 +1,263 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// Manager manages JetStream using a specific NATS connection and set of request options, unlike the
+// package level functions it never falls back to the connection set using Connect or SetConnection
+// and so multiple Managers can safely be used to manage different accounts or clusters
+type Manager struct {
+	nc    *nats.Conn
+	ropts []RequestOption
+}
+
+// New creates a Manager that uses nc for all interactions with JetStream, opts configure defaults like timeouts for every request.
+// Like SetConnection this forces the connection to use old style requests
+func New(nc *nats.Conn, opts ...RequestOption) (*Manager, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("nats connection is required")
+	}
+
+	// needed so that interest drops are observed by JetStream and pull messages reach the right requester
+	nc.Opts.UseOldRequestStyle = true
+
+	m := &Manager{
+		nc:    nc,
+		ropts: append([]RequestOption{WithConnection(nc)}, opts...),
+	}
+
+	_, err := newreqoptions(m.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Connection is the NATS connection used by the Manager
+func (m *Manager) Connection() *nats.Conn {
+	return m.nc
+}
+
+// combines the Manager options with opts, opts take precedence
+func (m *Manager) requestOpts(opts ...RequestOption) []RequestOption {
+	ropts := make([]RequestOption, 0, len(m.ropts)+len(opts))
+	ropts = append(ropts, m.ropts...)
+
+	return append(ropts, opts...)
+}
+
+func (m *Manager) streamOpts(opts ...StreamOption) []StreamOption {
+	return append([]StreamOption{StreamConnection(m.ropts...)}, opts...)
+}
+
+func (m *Manager) consumerOpts(opts ...ConsumerOption) []ConsumerOption {
+	return append([]ConsumerOption{ConsumerConnection(m.ropts...)}, opts...)
+}
+
+func (m *Manager) backupOpts(opts ...BackupOption) []BackupOption {
+	return append([]BackupOption{BackupConnection(m.ropts...)}, opts...)
+}
+
+func (m *Manager) reconcileOpts(opts ...ReconcileOption) []ReconcileOption {
+	return append([]ReconcileOption{ReconcileConnection(m.ropts...)}, opts...)
+}
+
+// IsJetStreamEnabled determines if JetStream is enabled for the account
+func (m *Manager) IsJetStreamEnabled(opts ...RequestOption) bool {
+	return IsJetStreamEnabled(m.requestOpts(opts...)...)
+}
+
+// JetStreamAccountInfo retrieves information about the account limits and more
+func (m *Manager) JetStreamAccountInfo(opts ...RequestOption) (info api.JetStreamAccountStats, err error) {
+	return JetStreamAccountInfo(m.requestOpts(opts...)...)
+}
+
+// IsKnownStream determines if a Stream is known
+func (m *Manager) IsKnownStream(stream string, opts ...RequestOption) (bool, error) {
+	return IsKnownStream(stream, m.requestOpts(opts...)...)
+}
+
+// IsKnownStreamTemplate determines if a StreamTemplate is known
+func (m *Manager) IsKnownStreamTemplate(template string, opts ...RequestOption) (bool, error) {
+	return IsKnownStreamTemplate(template, m.requestOpts(opts...)...)
+}
+
+// IsKnownConsumer determines if a Consumer is known for a specific Stream
+func (m *Manager) IsKnownConsumer(stream string, consumer string, opts ...RequestOption) (bool, error) {
+	return IsKnownConsumer(stream, consumer, m.requestOpts(opts...)...)
+}
+
+// StreamNames is a sorted list of all known Streams
+func (m *Manager) StreamNames(opts ...RequestOption) (streams []string, err error) {
+	return StreamNames(m.requestOpts(opts...)...)
+}
+
+// StreamTemplateNames is a sorted list of all known StreamTemplates
+func (m *Manager) StreamTemplateNames(opts ...RequestOption) (templates []string, err error) {
+	return StreamTemplateNames(m.requestOpts(opts...)...)
+}
+
+// ConsumerNames is a sorted list of all known Consumers within a Stream
+func (m *Manager) ConsumerNames(stream string, opts ...RequestOption) (consumers []string, err error) {
+	return ConsumerNames(stream, m.requestOpts(opts...)...)
+}
+
+// EachStream iterates over all known Streams
+func (m *Manager) EachStream(cb func(*Stream), opts ...RequestOption) (err error) {
+	return EachStream(cb, m.requestOpts(opts...)...)
+}
+
+// EachStreamTemplate iterates over all known Stream Templates
+func (m *Manager) EachStreamTemplate(cb func(*StreamTemplate), opts ...RequestOption) (err error) {
+	return EachStreamTemplate(cb, m.requestOpts(opts...)...)
+}
+
+// NewStream creates a new stream using DefaultStream as a starting template allowing adjustments to be made using options
+func (m *Manager) NewStream(name string, opts ...StreamOption) (stream *Stream, err error) {
+	return NewStream(name, m.streamOpts(opts...)...)
+}
+
+// NewStreamFromDefault creates a new stream based on a supplied template and options
+func (m *Manager) NewStreamFromDefault(name string, dflt api.StreamConfig, opts ...StreamOption) (stream *Stream, err error) {
+	return NewStreamFromDefault(name, dflt, m.streamOpts(opts...)...)
+}
+
+// LoadOrNewStream loads an existing stream or creates a new one matching opts
+func (m *Manager) LoadOrNewStream(name string, opts ...StreamOption) (stream *Stream, err error) {
+	return LoadOrNewStream(name, m.streamOpts(opts...)...)
+}
+
+// LoadOrNewStreamFromDefault loads an existing stream or creates a new one matching opts and template
+func (m *Manager) LoadOrNewStreamFromDefault(name string, dflt api.StreamConfig, opts ...StreamOption) (stream *Stream, err error) {
+	return LoadOrNewStreamFromDefault(name, dflt, m.streamOpts(opts...)...)
+}
+
+// LoadStream loads a stream by name
+func (m *Manager) LoadStream(name string, opts ...RequestOption) (stream *Stream, err error) {
+	return LoadStream(name, m.requestOpts(opts...)...)
+}
+
+// NewConsumer creates a consumer based on DefaultConsumer modified by opts
+func (m *Manager) NewConsumer(stream string, opts ...ConsumerOption) (consumer *Consumer, err error) {
+	return NewConsumer(stream, m.consumerOpts(opts...)...)
+}
+
+// NewConsumerFromDefault creates a new consumer based on a template config that gets modified by opts
+func (m *Manager) NewConsumerFromDefault(stream string, dflt api.ConsumerConfig, opts ...ConsumerOption) (consumer *Consumer, err error) {
+	return NewConsumerFromDefault(stream, dflt, m.consumerOpts(opts...)...)
+}
+
+// LoadOrNewConsumer loads a consumer by name if known else creates a new one with these properties
+func (m *Manager) LoadOrNewConsumer(stream string, name string, opts ...ConsumerOption) (consumer *Consumer, err error) {
+	return LoadOrNewConsumer(stream, name, m.consumerOpts(opts...)...)
+}
+
+// LoadOrNewConsumerFromDefault loads a consumer by name if known else creates a new one with these properties based on template
+func (m *Manager) LoadOrNewConsumerFromDefault(stream string, name string, template api.ConsumerConfig, opts ...ConsumerOption) (consumer *Consumer, err error) {
+	return LoadOrNewConsumerFromDefault(stream, name, template, m.consumerOpts(opts...)...)
+}
+
+// LoadConsumer loads a consumer by name
+func (m *Manager) LoadConsumer(stream string, name string, opts ...RequestOption) (consumer *Consumer, err error) {
+	return LoadConsumer(stream, name, m.requestOpts(opts...)...)
+}
+
+// NextMsg retrieves the next message from a pull-based Consumer
+func (m *Manager) NextMsg(stream string, consumer string, opts ...RequestOption) (msg *nats.Msg, err error) {
+	return NextMsg(stream, consumer, m.requestOpts(opts...)...)
+}
+
+// Publish publishes a message to a subject that is bound to a Stream and waits for JetStream to acknowledge that it was stored
+func (m *Manager) Publish(subject string, data []byte, opts ...RequestOption) (*api.PubAck, error) {
+	return Publish(subject, data, m.requestOpts(opts...)...)
+}
+
+// NewAsyncPublisher creates a publisher that allows window messages to be awaiting acknowledgement
+func (m *Manager) NewAsyncPublisher(window int, opts ...RequestOption) (*AsyncPublisher, error) {
+	return NewAsyncPublisher(window, m.requestOpts(opts...)...)
+}
+
+// FetchBatch requests up to n messages from a pull based Consumer in a single request
+func (m *Manager) FetchBatch(stream string, consumer string, n int, opts ...RequestOption) ([]*Msg, error) {
+	return FetchBatch(stream, consumer, n, m.requestOpts(opts...)...)
+}
+
+// NewStreamTemplate creates a new template
+func (m *Manager) NewStreamTemplate(name string, maxStreams uint32, config api.StreamConfig, opts ...StreamOption) (template *StreamTemplate, err error) {
+	return NewStreamTemplate(name, maxStreams, config, m.streamOpts(opts...)...)
+}
+
+// LoadOrNewStreamTemplate loads an existing template, else creates a new one based on config
+func (m *Manager) LoadOrNewStreamTemplate(name string, maxStreams uint32, config api.StreamConfig, opts ...StreamOption) (template *StreamTemplate, err error) {
+	return LoadOrNewStreamTemplate(name, maxStreams, config, m.streamOpts(opts...)...)
+}
+
+// LoadStreamTemplate loads a given stream template from JetStream
+func (m *Manager) LoadStreamTemplate(name string, opts ...RequestOption) (template *StreamTemplate, err error) {
+	return LoadStreamTemplate(name, m.requestOpts(opts...)...)
+}
+
+// BackupJetStreamConfiguration creates a backup of all configuration for Streams, Consumers and Stream Templates
+func (m *Manager) BackupJetStreamConfiguration(backupDir string, opts ...BackupOption) error {
+	return BackupJetStreamConfiguration(backupDir, m.backupOpts(opts...)...)
+}
+
+// RestoreJetStreamConfiguration restores the configuration from a backup made by BackupJetStreamConfiguration
+func (m *Manager) RestoreJetStreamConfiguration(backupDir string, update bool, opts ...BackupOption) error {
+	return RestoreJetStreamConfiguration(backupDir, update, m.backupOpts(opts...)...)
+}
+
+// RestoreJetStreamConfigurationFile restores a single file from a backup made by BackupJetStreamConfiguration
+func (m *Manager) RestoreJetStreamConfigurationFile(path string, update bool, opts ...BackupOption) error {
+	return RestoreJetStreamConfigurationFile(path, update, m.backupOpts(opts...)...)
+}
+
+// PlanJetStreamConfigurationRestore reports what RestoreJetStreamConfiguration would do without making any changes
+func (m *Manager) PlanJetStreamConfigurationRestore(backupDir string, update bool, opts ...BackupOption) (*RestorePlan, error) {
+	return PlanJetStreamConfigurationRestore(backupDir, update, m.backupOpts(opts...)...)
+}
+
+// BackupStreamData creates a backup of all the messages in a Stream into backupDir
+func (m *Manager) BackupStreamData(stream string, backupDir string, opts ...BackupOption) error {
+	return BackupStreamData(stream, backupDir, m.backupOpts(opts...)...)
+}
+
+// RestoreStreamData publishes all the messages from a backup made by BackupStreamData into a Stream
+func (m *Manager) RestoreStreamData(backupDir string, stream string, opts ...BackupOption) error {
+	return RestoreStreamData(backupDir, stream, m.backupOpts(opts...)...)
+}
+
+// PlanReconcile compares the desired state to the server and reports the changes Reconcile would make
+func (m *Manager) PlanReconcile(desired *DesiredState, opts ...ReconcileOption) (*ReconcilePlan, error) {
+	return PlanReconcile(desired, m.reconcileOpts(opts...)...)
+}
+
+// Reconcile makes the changes needed to bring the server to the desired state
+func (m *Manager) Reconcile(desired *DesiredState, opts ...ReconcileOption) (*ReconcilePlan, error) {
+	return Reconcile(desired, m.reconcileOpts(opts...)...)
+}
+
+// WatchStream polls a Stream and its Consumers and sends changes to the returned channel
+func (m *Manager) WatchStream(ctx context.Context, stream string, opts ...WatchOption) (<-chan WatchEvent, error) {
+	return WatchStream(ctx, stream, append([]WatchOption{WatchConnection(m.ropts...)}, opts...)...)
+}
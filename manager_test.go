@@ -0,0 +1,115 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestNew(t *testing.T) {
+	_, err := jsm.New(nil)
+	if err == nil {
+		t.Fatalf("expected an error for a nil connection")
+	}
+}
+
+func TestManager_Isolation(t *testing.T) {
+	gsrv, gnc := startJSServer(t)
+	defer gsrv.Shutdown()
+	defer gnc.Flush()
+
+	srv, nc := startIsolatedJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	mgr, err := jsm.New(nc, jsm.WithTimeout(2*time.Second))
+	checkErr(t, err, "manager failed")
+
+	if !mgr.IsJetStreamEnabled() {
+		t.Fatalf("expected JS to be enabled")
+	}
+
+	stream, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.NewConsumer(jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	names, err := mgr.StreamNames()
+	checkErr(t, err, "names failed")
+	if len(names) != 1 || names[0] != "ORDERS" {
+		t.Fatalf("expected [ORDERS] got %v", names)
+	}
+
+	names, err = jsm.StreamNames()
+	checkErr(t, err, "names failed")
+	if len(names) != 0 {
+		t.Fatalf("expected no streams on the package level connection got %v", names)
+	}
+
+	consumer, err := mgr.LoadConsumer("ORDERS", "NEW")
+	checkErr(t, err, "consumer load failed")
+	if consumer.Name() != "NEW" {
+		t.Fatalf("expected NEW got %s", consumer.Name())
+	}
+
+	info, err := mgr.JetStreamAccountInfo()
+	checkErr(t, err, "info failed")
+	if info.Streams != 1 {
+		t.Fatalf("expected 1 stream got %d", info.Streams)
+	}
+}
+
+func TestManager_BackupRestore(t *testing.T) {
+	gsrv, gnc := startJSServer(t)
+	defer gsrv.Shutdown()
+	defer gnc.Flush()
+
+	srv, nc := startIsolatedJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	mgr, err := jsm.New(nc)
+	checkErr(t, err, "manager failed")
+
+	_, err = mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	dir := filepath.Join(td, "backup")
+	err = mgr.BackupJetStreamConfiguration(dir)
+	checkErr(t, err, "backup failed")
+
+	// restores onto the package level connection
+	err = jsm.RestoreJetStreamConfiguration(dir, false)
+	checkErr(t, err, "restore failed")
+
+	known, err := jsm.IsKnownConsumer("ORDERS", "NEW")
+	checkErr(t, err, "known failed")
+	if !known {
+		t.Fatalf("NEW was not restored")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// ErrAlreadyAcknowledged is returned when acknowledging a message that was already acknowledged or NAKed
+var ErrAlreadyAcknowledged = errors.New("message already acknowledged")
+
+// Msg is a message received from a Consumer with helpers to acknowledge it, a message can only be
+// acknowledged once but progress can be reported any number of times before that
+type Msg struct {
+	*nats.Msg
+
+	ropts *reqoptions
+
+	mu       sync.Mutex
+	acked    bool
+	response []byte
+	info     *MsgInfo
+	infoErr  error
+	parsed   bool
+}
+
+// NewMsg wraps a message received from a Consumer, the connection set in opts is used by AckSync and AckNext
+func NewMsg(m *nats.Msg, opts ...RequestOption) *Msg {
+	// without a connection only the asynchronous acknowledgements will work
+	ropts, _ := newreqoptions(opts...)
+
+	return newMsg(m, ropts)
+}
+
+func newMsg(m *nats.Msg, ropts *reqoptions) *Msg {
+	return &Msg{Msg: m, ropts: ropts}
+}
+
+// Metadata parses the message metadata from the reply subject, see ParseJSMsgMetadata
+func (m *Msg) Metadata() (*MsgInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parsed {
+		m.info, m.infoErr = ParseJSMsgMetadata(m.Msg)
+		m.parsed = true
+	}
+
+	return m.info, m.infoErr
+}
+
+// IsAcknowledged determines if the message was already acknowledged or NAKed
+func (m *Msg) IsAcknowledged() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.acked
+}
+
+// Ack acknowledges the message
+func (m *Msg) Ack() error {
+	return m.respond(api.AckAck, true)
+}
+
+// Nak tells the server the message was not processed, it will be redelivered
+func (m *Msg) Nak() error {
+	return m.respond(api.AckNak, true)
+}
+
+// InProgress tells the server the message is still being worked on, resetting its AckWait
+func (m *Msg) InProgress() error {
+	return m.respond(api.AckProgress, false)
+}
+
+// AckSync acknowledges the message and waits until the server received the acknowledgement.
+//
+// The server does not respond to acknowledgements so this flushes the connection, an acknowledgement
+// for a message that is no longer pending is silently dropped by the server
+func (m *Msg) AckSync(opts ...RequestOption) error {
+	return m.respondSync(api.AckAck, opts...)
+}
+
+// AckNext acknowledges the message and requests the next message from the same pull based Consumer
+func (m *Msg) AckNext(opts ...RequestOption) (*Msg, error) {
+	ropts, err := m.requestOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.guard(api.AckNext, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := ropts.context()
+	defer cancel()
+
+	// the ack subject is used as is, it's the subject the server told us to use
+	res, err := ropts.nc.RequestWithContext(ctx, m.Reply, api.AckNext)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ParseErrorResponse(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMsg(res, ropts), nil
+}
+
+// respondSync sends a final acknowledgement and flushes the connection
+func (m *Msg) respondSync(data []byte, opts ...RequestOption) error {
+	ropts, err := m.requestOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	err = m.respond(data, true)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := ropts.context()
+	defer cancel()
+
+	return ropts.nc.FlushWithContext(ctx)
+}
+
+func (m *Msg) respond(data []byte, final bool) error {
+	err := m.guard(data, final)
+	if err != nil {
+		return err
+	}
+
+	return m.Respond(data)
+}
+
+// finalResponse is the acknowledgement that was sent, nil when the message was not acknowledged yet
+func (m *Msg) finalResponse() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.response
+}
+
+// guard fails when the message was already acknowledged and marks it acknowledged with data if final
+func (m *Msg) guard(data []byte, final bool) error {
+	if m.Msg == nil || m.Reply == "" {
+		return fmt.Errorf("message can not be acknowledged, it has no reply subject")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.acked {
+		return ErrAlreadyAcknowledged
+	}
+
+	if final {
+		m.acked = true
+		m.response = data
+	}
+
+	return nil
+}
+
+func (m *Msg) requestOptions(opts ...RequestOption) (*reqoptions, error) {
+	ropts := dfltreqoptions()
+	if m.ropts != nil {
+		ropts = m.ropts.clone()
+	}
+
+	for _, opt := range opts {
+		opt(ropts)
+	}
+
+	if ropts.nc == nil {
+		return nil, fmt.Errorf("no NATS connection supplied")
+	}
+
+	return ropts, nil
+}
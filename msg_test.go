@@ -0,0 +1,129 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestMsg_Acknowledgements(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("ACKS"), jsm.DeliverAllAvailable(), jsm.AckWait(500*time.Millisecond))
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 3; i++ {
+		_, err = nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	msg, err := consumer.NextJSMsg()
+	checkErr(t, err, "next failed")
+
+	info, err := msg.Metadata()
+	checkErr(t, err, "metadata failed")
+	if info.Stream() != "ORDERS" || info.Consumer() != "ACKS" || info.ConsumerSequence() != 1 {
+		t.Fatalf("invalid metadata %+v", info)
+	}
+
+	checkErr(t, msg.InProgress(), "progress failed")
+	checkErr(t, msg.AckSync(), "ack failed")
+
+	if !msg.IsAcknowledged() {
+		t.Fatalf("expected message to be acknowledged")
+	}
+
+	for _, ack := range []func() error{msg.Ack, msg.Nak, msg.InProgress} {
+		if !errors.Is(ack(), jsm.ErrAlreadyAcknowledged) {
+			t.Fatalf("expected double acknowledgement to fail")
+		}
+	}
+
+	// ack the 2nd message and receive the 3rd in one go
+	msg, err = consumer.NextJSMsg()
+	checkErr(t, err, "next failed")
+
+	next, err := msg.AckNext()
+	checkErr(t, err, "ack next failed")
+	if string(next.Data) != "2" {
+		t.Fatalf("expected message 2 got %q", next.Data)
+	}
+
+	// a nak redelivers the message
+	checkErr(t, next.Nak(), "nak failed")
+
+	msg, err = consumer.NextJSMsg()
+	checkErr(t, err, "next failed")
+	info, err = msg.Metadata()
+	checkErr(t, err, "metadata failed")
+	if string(msg.Data) != "2" || info.Delivered() != 2 {
+		t.Fatalf("expected redelivery of message 2 got %q delivered %d", msg.Data, info.Delivered())
+	}
+	checkErr(t, msg.AckSync(), "ack failed")
+
+	state, err := consumer.State()
+	checkErr(t, err, "state failed")
+	if len(state.Pending) != 0 {
+		t.Fatalf("expected no pending messages got %v", state.Pending)
+	}
+}
+
+func TestMsg_NoReply(t *testing.T) {
+	msg := jsm.NewMsg(&nats.Msg{Subject: "ORDERS.new", Data: []byte("1")})
+	if msg.Ack() == nil {
+		t.Fatalf("expected ack without a reply subject to fail")
+	}
+
+	_, err := msg.Metadata()
+	if err == nil {
+		t.Fatalf("expected metadata without a reply subject to fail")
+	}
+}
+
+func TestMsg_ConcurrentRequestOptions(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	observer := func(ctx context.Context, _ string, _ []byte) (context.Context, func(jsm.RequestTrace)) {
+		return ctx, nil
+	}
+
+	// three observers leave room in the slice, appending per call options to a shared slice races
+	msg := jsm.NewMsg(&nats.Msg{Subject: "ORDERS.new", Data: []byte("1")}, jsm.WithConnection(nc), jsm.WithRequestObserver(observer), jsm.WithRequestObserver(observer), jsm.WithRequestObserver(observer))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg.AckSync(jsm.WithRequestObserver(observer))
+		}()
+	}
+
+	wg.Wait()
+}
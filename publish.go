@@ -0,0 +1,286 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// Publish publishes a message to a subject that is bound to a Stream and waits for JetStream to acknowledge that it was stored,
+// use WithMsgID and WithDedupCache to avoid storing duplicates when retrying a publish
+func Publish(subject string, data []byte, opts ...RequestOption) (*api.PubAck, error) {
+	ropts, err := newreqoptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ropts.msgID != "" {
+		if ropts.dedup == nil {
+			return nil, fmt.Errorf("message IDs require a DedupCache, the NATS client in use does not support headers")
+		}
+
+		ack, ok := ropts.dedup.reserve(ropts.msgID)
+		if ok {
+			return ack, nil
+		}
+	}
+
+	ack, err := publish(subject, data, ropts)
+
+	if ropts.msgID != "" {
+		ropts.dedup.complete(ropts.msgID, ack)
+	}
+
+	return ack, err
+}
+
+func publish(subject string, data []byte, ropts *reqoptions) (*api.PubAck, error) {
+	res, err := request(subject, data, ropts)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePubAck(res)
+}
+
+// Publish publishes a message and verifies it was stored in this Stream
+func (s *Stream) Publish(subject string, data []byte, opts ...RequestOption) (*api.PubAck, error) {
+	ack, err := Publish(subject, data, append(append([]RequestOption{}, s.cfg.ropts...), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ack.Stream != s.Name() {
+		return ack, fmt.Errorf("message was stored in Stream %s and not %s", ack.Stream, s.Name())
+	}
+
+	return ack, nil
+}
+
+// ErrPublisherClosed is the error for publishes that were outstanding when an AsyncPublisher was closed
+var ErrPublisherClosed = fmt.Errorf("publisher closed")
+
+// PubAckFuture is the pending result of a message published using an AsyncPublisher
+type PubAckFuture struct {
+	Subject string
+
+	done  chan struct{}
+	ack   *api.PubAck
+	err   error
+	timer *time.Timer
+}
+
+// Done is closed once the message was acknowledged or failed
+func (f *PubAckFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Ack waits for the acknowledgement and returns it
+func (f *PubAckFuture) Ack() (*api.PubAck, error) {
+	<-f.done
+
+	return f.ack, f.err
+}
+
+// AsyncPublisher publishes messages without waiting for each acknowledgement, at most a fixed number of messages
+// can be awaiting acknowledgement after which Publish blocks
+type AsyncPublisher struct {
+	ropts  *reqoptions
+	stream string
+	prefix string
+	sub    *nats.Subscription
+	slots  chan struct{}
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]*PubAckFuture
+	closed  bool
+}
+
+// NewAsyncPublisher creates a publisher that allows window messages to be awaiting acknowledgement, each message
+// fails with a timeout if it's not acknowledged within the request timeout
+func NewAsyncPublisher(window int, opts ...RequestOption) (*AsyncPublisher, error) {
+	if window < 1 {
+		return nil, fmt.Errorf("window has to be at least 1")
+	}
+
+	ropts, err := newreqoptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &AsyncPublisher{
+		ropts:   ropts,
+		prefix:  nats.NewInbox(),
+		slots:   make(chan struct{}, window),
+		pending: make(map[string]*PubAckFuture),
+	}
+
+	p.sub, err = ropts.nc.Subscribe(p.prefix+".*", p.handleAck)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// NewAsyncPublisher creates an AsyncPublisher that verifies messages are stored in this Stream
+func (s *Stream) NewAsyncPublisher(window int, opts ...RequestOption) (*AsyncPublisher, error) {
+	p, err := NewAsyncPublisher(window, append(append([]RequestOption{}, s.cfg.ropts...), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	p.stream = s.Name()
+
+	return p, nil
+}
+
+// Publish publishes a message, blocking while the window of messages awaiting acknowledgement is full
+func (p *AsyncPublisher) Publish(subject string, data []byte) (*PubAckFuture, error) {
+	return p.PublishWithContext(context.Background(), subject, data)
+}
+
+// PublishWithContext publishes a message, blocking while the window of messages awaiting acknowledgement is full
+// or until ctx is done
+func (p *AsyncPublisher) PublishWithContext(ctx context.Context, subject string, data []byte) (*PubAckFuture, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, ErrPublisherClosed
+	}
+
+	p.seq++
+	reply := p.prefix + "." + strconv.FormatUint(p.seq, 10)
+	future := &PubAckFuture{Subject: subject, done: make(chan struct{})}
+	p.pending[reply] = future
+	p.mu.Unlock()
+
+	err := p.ropts.nc.PublishRequest(subject, reply, data)
+	if err != nil {
+		p.complete(reply, nil, err)
+		return nil, err
+	}
+
+	// the ack might already have been handled, only start the timeout for messages still awaiting it
+	p.mu.Lock()
+	if _, ok := p.pending[reply]; ok {
+		future.timer = time.AfterFunc(p.ropts.timeout, func() { p.complete(reply, nil, nats.ErrTimeout) })
+	}
+	p.mu.Unlock()
+
+	return future, nil
+}
+
+// Outstanding is the number of messages awaiting acknowledgement
+func (p *AsyncPublisher) Outstanding() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.pending)
+}
+
+// Flush waits until all messages published so far are acknowledged or failed
+func (p *AsyncPublisher) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	futures := make([]*PubAckFuture, 0, len(p.pending))
+	for _, f := range p.pending {
+		futures = append(futures, f)
+	}
+	p.mu.Unlock()
+
+	for _, f := range futures {
+		select {
+		case <-f.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Close stops the publisher, messages still awaiting acknowledgement fail with ErrPublisherClosed
+func (p *AsyncPublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+
+	replies := make([]string, 0, len(p.pending))
+	for reply := range p.pending {
+		replies = append(replies, reply)
+	}
+	p.mu.Unlock()
+
+	for _, reply := range replies {
+		p.complete(reply, nil, ErrPublisherClosed)
+	}
+
+	return p.sub.Unsubscribe()
+}
+
+func (p *AsyncPublisher) handleAck(m *nats.Msg) {
+	if !strings.HasPrefix(m.Subject, p.prefix+".") {
+		return
+	}
+
+	ack, err := parsePubAck(m)
+	if err == nil && p.stream != "" && ack.Stream != p.stream {
+		err = fmt.Errorf("message was stored in Stream %s and not %s", ack.Stream, p.stream)
+	}
+
+	p.complete(m.Subject, ack, err)
+}
+
+// complete resolves the future for reply once, later acknowledgements or timeouts are ignored
+func (p *AsyncPublisher) complete(reply string, ack *api.PubAck, err error) {
+	p.mu.Lock()
+	future, ok := p.pending[reply]
+	if ok {
+		delete(p.pending, reply)
+		if future.timer != nil {
+			future.timer.Stop()
+		}
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	future.ack = ack
+	future.err = err
+	close(future.done)
+
+	<-p.slots
+}
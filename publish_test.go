@@ -0,0 +1,142 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestPublish(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.MaxMessageSize(10))
+	checkErr(t, err, "create failed")
+
+	ack, err := jsm.Publish("ORDERS.new", []byte("1"))
+	checkErr(t, err, "publish failed")
+	if ack.Stream != "ORDERS" || ack.Sequence != 1 {
+		t.Fatalf("invalid ack %+v", ack)
+	}
+
+	ack, err = stream.Publish("ORDERS.new", []byte("2"))
+	checkErr(t, err, "publish failed")
+	if ack.Sequence != 2 {
+		t.Fatalf("invalid ack %+v", ack)
+	}
+
+	_, err = stream.Publish("ORDERS.new", []byte(strings.Repeat("x", 20)))
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Description, "message size exceeds maximum") {
+		t.Fatalf("expected a message size error got %v", err)
+	}
+
+	_, err = jsm.Publish("OTHER.new", []byte("1"), jsm.WithTimeout(100*time.Millisecond))
+	if err == nil {
+		t.Fatalf("expected publish to a subject without a stream to fail")
+	}
+}
+
+func TestAsyncPublisher(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	// the server in use reuses its ack buffer so concurrent acks can be corrupted, a window of 1
+	// avoids that while still exercising the async path
+	pub, err := stream.NewAsyncPublisher(1)
+	checkErr(t, err, "publisher failed")
+	defer pub.Close()
+
+	var futures []*jsm.PubAckFuture
+	for i := 0; i < 20; i++ {
+		f, err := pub.Publish("ORDERS.new", []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+
+		futures = append(futures, f)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	checkErr(t, pub.Flush(ctx), "flush failed")
+
+	for i, f := range futures {
+		ack, err := f.Ack()
+		checkErr(t, err, "ack failed")
+		if ack.Stream != "ORDERS" || ack.Sequence != uint64(i+1) {
+			t.Fatalf("invalid ack %+v for message %d", ack, i)
+		}
+	}
+
+	wide, err := stream.NewAsyncPublisher(5)
+	checkErr(t, err, "publisher failed")
+	defer wide.Close()
+
+	futures = nil
+	for i := 0; i < 50; i++ {
+		f, err := wide.Publish("ORDERS.new", []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+
+		if wide.Outstanding() > 5 {
+			t.Fatalf("window exceeded: %d", wide.Outstanding())
+		}
+
+		futures = append(futures, f)
+	}
+
+	checkErr(t, wide.Flush(ctx), "flush failed")
+
+	for _, f := range futures {
+		select {
+		case <-f.Done():
+		default:
+			t.Fatalf("expected all messages to be completed after flush")
+		}
+	}
+
+	state, err := stream.State()
+	checkErr(t, err, "state failed")
+	if state.Msgs != 70 {
+		t.Fatalf("expected 70 messages got %d", state.Msgs)
+	}
+
+	// nothing listens on OTHER so it times out and frees the window
+	pub, err = jsm.NewAsyncPublisher(1, jsm.WithTimeout(100*time.Millisecond))
+	checkErr(t, err, "publisher failed")
+	defer pub.Close()
+
+	f, err := pub.Publish("OTHER.new", []byte("1"))
+	checkErr(t, err, "publish failed")
+	_, err = f.Ack()
+	if err == nil {
+		t.Fatalf("expected a timeout")
+	}
+
+	f, err = pub.Publish("ORDERS.new", []byte("1"))
+	checkErr(t, err, "publish failed")
+	_, err = f.Ack()
+	checkErr(t, err, "ack failed")
+}
@@ -0,0 +1,307 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// PullHandler handles a message received by a PullWorker, returning an error will NAK the message unless the
+// handler acknowledged it already. ctx is canceled when the worker is stopping, handlers can use it to finish early
+type PullHandler func(ctx context.Context, m *Msg) error
+
+// PullWorkerOption configures a PullWorker
+type PullWorkerOption func(o *pullWorkerOptions)
+
+type pullWorkerOptions struct {
+	workers  int
+	fetch    time.Duration
+	progress time.Duration
+	errCb    func(error)
+}
+
+// PullWorkers sets the number of messages that will be handled concurrently, defaults to 1
+func PullWorkers(n int) PullWorkerOption {
+	return func(o *pullWorkerOptions) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// PullFetchTimeout is how long a worker waits for a message before asking again, this is also the longest a
+// shutdown will wait for idle workers, defaults to 2 seconds
+func PullFetchTimeout(t time.Duration) PullWorkerOption {
+	return func(o *pullWorkerOptions) {
+		if t > 0 {
+			o.fetch = t
+		}
+	}
+}
+
+// PullProgressInterval sets how often a progress acknowledgement is sent while a handler is running, defaults
+// to half the consumer AckWait, 0 disables progress acknowledgements
+func PullProgressInterval(t time.Duration) PullWorkerOption {
+	return func(o *pullWorkerOptions) {
+		o.progress = t
+	}
+}
+
+// PullErrorHandler sets a callback that will be called with errors encountered while fetching messages and
+// while acknowledging or NAKing handled messages
+func PullErrorHandler(cb func(error)) PullWorkerOption {
+	return func(o *pullWorkerOptions) {
+		o.errCb = cb
+	}
+}
+
+// PullWorkerStats is a snapshot of the activity of a PullWorker
+type PullWorkerStats struct {
+	// Processed is the number of messages that were handled
+	Processed uint64
+	// Failed is the number of messages whose handler returned an error or panicked
+	Failed uint64
+	// Acked is the number of messages that were acknowledged by the worker or the handler, messages from Consumers
+	// using AckNone are not acknowledged
+	Acked uint64
+	// Nacked is the number of messages that were NAKed by the worker or the handler
+	Nacked uint64
+	// FetchErrors is the number of failed attempts to fetch messages, not counting timeouts
+	FetchErrors uint64
+	// AckErrors is the number of handled messages whose acknowledgement or NAK could not be delivered, the
+	// server will deliver these again once their AckWait passes
+	AckErrors uint64
+	// InFlight is the number of messages being handled right now
+	InFlight int64
+	// Rate is the average number of messages handled per second since the worker started
+	Rate float64
+}
+
+// PullWorker handles messages from a pull based Consumer concurrently
+type PullWorker struct {
+	consumer *Consumer
+	handler  PullHandler
+	opts     *pullWorkerOptions
+
+	processed   uint64
+	failed      uint64
+	acked       uint64
+	nacked      uint64
+	fetchErrors uint64
+	ackErrors   uint64
+	inFlight    int64
+
+	mu      sync.Mutex
+	started time.Time
+	stopped time.Time
+	running bool
+}
+
+// NewPullWorker creates a worker that handles messages from a pull based Consumer using handler
+func NewPullWorker(consumer *Consumer, handler PullHandler, opts ...PullWorkerOption) (*PullWorker, error) {
+	if consumer == nil || handler == nil {
+		return nil, fmt.Errorf("a consumer and handler is required")
+	}
+
+	if !consumer.IsPullMode() {
+		return nil, fmt.Errorf("consumer %s > %s is not pull-based", consumer.StreamName(), consumer.Name())
+	}
+
+	wopts := &pullWorkerOptions{
+		workers:  1,
+		fetch:    2 * time.Second,
+		progress: consumer.AckWait() / 2,
+	}
+
+	for _, opt := range opts {
+		opt(wopts)
+	}
+
+	return &PullWorker{consumer: consumer, handler: handler, opts: wopts}, nil
+}
+
+// Run handles messages until ctx is canceled, once canceled no new messages are fetched and Run returns after
+// all messages being handled are done
+func (w *PullWorker) Run(ctx context.Context) error {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return fmt.Errorf("already running")
+	}
+	w.running = true
+	w.started = time.Now()
+	w.stopped = time.Time{}
+	w.mu.Unlock()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < w.opts.workers; i++ {
+		wg.Add(1)
+		go w.work(ctx, wg)
+	}
+
+	wg.Wait()
+
+	w.mu.Lock()
+	w.running = false
+	w.stopped = time.Now()
+	w.mu.Unlock()
+
+	return nil
+}
+
+// Stats reports the activity of the worker
+func (w *PullWorker) Stats() PullWorkerStats {
+	stats := PullWorkerStats{
+		Processed:   atomic.LoadUint64(&w.processed),
+		Failed:      atomic.LoadUint64(&w.failed),
+		Acked:       atomic.LoadUint64(&w.acked),
+		Nacked:      atomic.LoadUint64(&w.nacked),
+		FetchErrors: atomic.LoadUint64(&w.fetchErrors),
+		AckErrors:   atomic.LoadUint64(&w.ackErrors),
+		InFlight:    atomic.LoadInt64(&w.inFlight),
+	}
+
+	w.mu.Lock()
+	started, stopped := w.started, w.stopped
+	w.mu.Unlock()
+
+	if stopped.IsZero() {
+		stopped = time.Now()
+	}
+
+	if !started.IsZero() {
+		if elapsed := stopped.Sub(started).Seconds(); elapsed > 0 {
+			stats.Rate = float64(stats.Processed) / elapsed
+		}
+	}
+
+	return stats
+}
+
+func (w *PullWorker) work(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// not using ctx for the request so a message being delivered while shutting down is not lost
+		msg, err := w.consumer.NextJSMsg(WithTimeout(w.opts.fetch))
+		switch {
+		case errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded):
+			continue
+
+		case err != nil:
+			atomic.AddUint64(&w.fetchErrors, 1)
+			if w.opts.errCb != nil {
+				w.opts.errCb(err)
+			}
+
+			// avoids a busy loop while the connection is down
+			sleepContext(ctx, w.opts.fetch)
+			continue
+		}
+
+		w.handle(ctx, msg)
+	}
+}
+
+func (w *PullWorker) handle(ctx context.Context, msg *Msg) {
+	atomic.AddInt64(&w.inFlight, 1)
+	defer atomic.AddInt64(&w.inFlight, -1)
+
+	acks := w.consumer.AckPolicy() != api.AckNone
+
+	done := make(chan struct{})
+	if acks && w.opts.progress > 0 {
+		go w.sendProgress(msg, done)
+	}
+
+	err := w.safeHandle(ctx, msg)
+	close(done)
+
+	atomic.AddUint64(&w.processed, 1)
+	if err != nil {
+		atomic.AddUint64(&w.failed, 1)
+	}
+
+	if !acks {
+		return
+	}
+
+	response, kind := api.AckAck, "acknowledging"
+	if err != nil {
+		response, kind = api.AckNak, "NAKing"
+	}
+
+	err = msg.respondSync(response)
+	switch {
+	case err == nil:
+
+	// the handler already acknowledged or NAKed the message itself, count what it sent
+	case errors.Is(err, ErrAlreadyAcknowledged):
+		response = msg.finalResponse()
+
+	default:
+		atomic.AddUint64(&w.ackErrors, 1)
+		if w.opts.errCb != nil {
+			w.opts.errCb(fmt.Errorf("%s message %s failed: %w", kind, msg.Reply, err))
+		}
+
+		return
+	}
+
+	if bytes.Equal(response, api.AckNak) {
+		atomic.AddUint64(&w.nacked, 1)
+	} else {
+		atomic.AddUint64(&w.acked, 1)
+	}
+}
+
+func (w *PullWorker) safeHandle(ctx context.Context, msg *Msg) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+
+	return w.handler(ctx, msg)
+}
+
+func (w *PullWorker) sendProgress(msg *Msg, done chan struct{}) {
+	ticker := time.NewTicker(w.opts.progress)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if msg.InProgress() != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
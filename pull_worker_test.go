@@ -0,0 +1,251 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestPullWorker(t *testing.T) {
+	srv, nc, stream := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("WORKER"), jsm.DeliverAllAvailable(), jsm.AckWait(time.Second))
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 20; i++ {
+		_, err = nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mu := sync.Mutex{}
+	seen := map[string]int{}
+
+	handler := func(_ context.Context, m *jsm.Msg) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		seen[string(m.Data)]++
+
+		// fails the first delivery of message 5
+		if string(m.Data) == "5" && seen["5"] == 1 {
+			return errors.New("simulated failure")
+		}
+
+		// a slow handler that needs progress acks to not be redelivered
+		if string(m.Data) == "10" {
+			mu.Unlock()
+			time.Sleep(1500 * time.Millisecond)
+			mu.Lock()
+		}
+
+		if len(seen) == 20 && seen["5"] == 2 {
+			cancel()
+		}
+
+		return nil
+	}
+
+	worker, err := jsm.NewPullWorker(consumer, handler, jsm.PullWorkers(4), jsm.PullFetchTimeout(250*time.Millisecond), jsm.PullProgressInterval(250*time.Millisecond))
+	checkErr(t, err, "worker failed")
+
+	err = worker.Run(ctx)
+	checkErr(t, err, "run failed")
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("worker did not handle all messages: %v", seen)
+	}
+
+	if seen["10"] != 1 {
+		t.Fatalf("expected message 10 to be handled once got %d", seen["10"])
+	}
+
+	stats := worker.Stats()
+	if stats.Processed != 21 || stats.Failed != 1 || stats.Acked != 20 || stats.Nacked != 1 || stats.InFlight != 0 || stats.Rate == 0 {
+		t.Fatalf("invalid stats %+v", stats)
+	}
+
+	checkErr(t, nc.Flush(), "flush failed")
+
+	state, err := consumer.State()
+	checkErr(t, err, "state failed")
+	if len(state.Pending) != 0 || state.Delivered.StreamSeq != 21 {
+		t.Fatalf("expected all messages to be acknowledged got %+v", state)
+	}
+}
+
+func TestPullWorker_PushConsumer(t *testing.T) {
+	srv, nc, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("PUSH"), jsm.DeliverySubject("out"))
+	checkErr(t, err, "create failed")
+
+	_, err = jsm.NewPullWorker(consumer, func(context.Context, *jsm.Msg) error { return nil })
+	if err == nil {
+		t.Fatalf("expected push consumer to fail")
+	}
+}
+
+func TestPullWorker_ConcurrentWorkers(t *testing.T) {
+	srv, nc, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	// several options leave spare capacity in the shared option slices, workers appending to them would race
+	mgr, err := jsm.New(nc, jsm.WithTimeout(time.Second), jsm.WithTimeout(2*time.Second), jsm.WithTimeout(3*time.Second), jsm.WithTimeout(5*time.Second))
+	checkErr(t, err, "manager failed")
+
+	stream, err := mgr.LoadStream("ORDERS")
+	checkErr(t, err, "load failed")
+
+	consumer, err := stream.NewConsumer(jsm.DurableName("WORKERS"), jsm.DeliverAllAvailable())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 50; i++ {
+		_, err = stream.Publish("ORDERS.new", []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mu := sync.Mutex{}
+	seen := map[string]bool{}
+
+	worker, err := jsm.NewPullWorker(consumer, func(_ context.Context, m *jsm.Msg) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		seen[string(m.Data)] = true
+		if len(seen) == 50 {
+			cancel()
+		}
+
+		return nil
+	}, jsm.PullWorkers(8), jsm.PullFetchTimeout(250*time.Millisecond))
+	checkErr(t, err, "worker failed")
+
+	err = worker.Run(ctx)
+	checkErr(t, err, "run failed")
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("worker did not handle all messages, handled %d", len(seen))
+	}
+}
+
+func TestPullWorker_AckErrors(t *testing.T) {
+	srv, nc, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	wnc, err := nats.Connect(srv.ClientURL(), nats.UseOldRequestStyle())
+	checkErr(t, err, "connect failed")
+	defer wnc.Close()
+
+	consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName("WORKER"), jsm.DeliverAllAvailable(), jsm.ConsumerConnection(jsm.WithConnection(wnc)))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var errs []error
+	mu := sync.Mutex{}
+
+	// closing the connection the message arrived on makes the acknowledgement fail
+	worker, err := jsm.NewPullWorker(consumer, func(_ context.Context, _ *jsm.Msg) error {
+		wnc.Close()
+		cancel()
+		return nil
+	}, jsm.PullFetchTimeout(250*time.Millisecond), jsm.PullErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	checkErr(t, err, "worker failed")
+
+	checkErr(t, worker.Run(ctx), "run failed")
+
+	stats := worker.Stats()
+	if stats.Processed != 1 || stats.Acked != 0 || stats.AckErrors != 1 {
+		t.Fatalf("expected a failed acknowledgement got %+v", stats)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], nats.ErrConnectionClosed) {
+		t.Fatalf("expected the acknowledgement error to be reported got %v", errs)
+	}
+}
+
+func TestPullWorker_HandlerAcknowledged(t *testing.T) {
+	srv, nc, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	run := func(name string, handler jsm.PullHandler) jsm.PullWorkerStats {
+		t.Helper()
+
+		consumer, err := jsm.NewConsumer("ORDERS", jsm.DurableName(name), jsm.DeliverAllAvailable())
+		checkErr(t, err, "create failed")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		worker, err := jsm.NewPullWorker(consumer, func(ctx context.Context, m *jsm.Msg) error {
+			defer cancel()
+			return handler(ctx, m)
+		}, jsm.PullFetchTimeout(250*time.Millisecond))
+		checkErr(t, err, "worker failed")
+
+		checkErr(t, worker.Run(ctx), "run failed")
+
+		return worker.Stats()
+	}
+
+	// the handler acknowledgement is counted and not overridden by the failure
+	stats := run("ACKED", func(_ context.Context, m *jsm.Msg) error {
+		checkErr(t, m.Ack(), "ack failed")
+		return errors.New("simulated failure")
+	})
+
+	if stats.Processed != 1 || stats.Failed != 1 || stats.Acked != 1 || stats.Nacked != 0 {
+		t.Fatalf("expected 1 failed but acknowledged message got %+v", stats)
+	}
+
+	stats = run("NACKED", func(_ context.Context, m *jsm.Msg) error {
+		checkErr(t, m.Nak(), "nak failed")
+		return nil
+	})
+
+	if stats.Processed != 1 || stats.Failed != 0 || stats.Acked != 0 || stats.Nacked != 1 {
+		t.Fatalf("expected 1 NAKed message got %+v", stats)
+	}
+}
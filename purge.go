@@ -0,0 +1,233 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// PurgeOption configures a partial purge
+type PurgeOption func(o *purgeOptions)
+
+type purgeOptions struct {
+	req    api.StreamPurgeRequest
+	dryRun bool
+	ctx    context.Context
+}
+
+// PurgeSubject only purges messages matching subject, wildcards are supported
+func PurgeSubject(subject string) PurgeOption {
+	return func(o *purgeOptions) {
+		o.req.Subject = subject
+	}
+}
+
+// PurgeUpToSequence only purges messages with a sequence lower than seq
+func PurgeUpToSequence(seq uint64) PurgeOption {
+	return func(o *purgeOptions) {
+		o.req.Sequence = seq
+	}
+}
+
+// PurgeKeep keeps the n most recent messages that would otherwise be purged
+func PurgeKeep(n uint64) PurgeOption {
+	return func(o *purgeOptions) {
+		o.req.Keep = n
+	}
+}
+
+// PurgeDryRun scans the Stream and reports what would be purged without removing anything
+func PurgeDryRun() PurgeOption {
+	return func(o *purgeOptions) {
+		o.dryRun = true
+	}
+}
+
+// PurgeContext sets a context used while scanning the Stream
+func PurgeContext(ctx context.Context) PurgeOption {
+	return func(o *purgeOptions) {
+		o.ctx = ctx
+	}
+}
+
+// PurgeResult describes the messages removed by a partial purge
+type PurgeResult struct {
+	// Messages is the number of messages removed
+	Messages uint64 `json:"messages"`
+	// Bytes is the storage used by the removed messages
+	Bytes uint64 `json:"bytes"`
+	// Sequences are the removed messages, only known when the Stream was scanned
+	Sequences []uint64 `json:"sequences,omitempty"`
+	// DryRun indicates nothing was removed
+	DryRun bool `json:"dry_run"`
+	// ClientSide indicates the messages were deleted one by one as the server does not support partial purges
+	ClientSide bool `json:"client_side"`
+}
+
+// PurgeMessages removes a subset of the messages in the Stream, without options it behaves like Purge.
+//
+// Servers that do not support partial purges reject the request, in that case the Stream is scanned and matching
+// messages are deleted one by one. With PurgeDryRun the Stream is always scanned
+func (s *Stream) PurgeMessages(opts ...PurgeOption) (*PurgeResult, error) {
+	popts := &purgeOptions{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(popts)
+	}
+
+	if popts.dryRun {
+		res, _, err := s.scanForPurge(popts)
+		return res, err
+	}
+
+	before, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	if popts.req == (api.StreamPurgeRequest{}) {
+		err = s.Purge()
+	} else {
+		err = s.nativePurge(popts.req)
+	}
+
+	switch {
+	case errors.Is(err, api.ErrBadRequest):
+		return s.clientSidePurge(popts)
+
+	case err != nil:
+		return nil, err
+	}
+
+	after, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PurgeResult{}
+	if before.Msgs > after.Msgs {
+		res.Messages = before.Msgs - after.Msgs
+	}
+	if before.Bytes > after.Bytes {
+		res.Bytes = before.Bytes - after.Bytes
+	}
+
+	return res, nil
+}
+
+func (s *Stream) nativePurge(req api.StreamPurgeRequest) error {
+	jreq, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = request(fmt.Sprintf(api.JetStreamPurgeStreamT, s.Name()), jreq, s.cfg.conn)
+
+	return err
+}
+
+func (s *Stream) clientSidePurge(popts *purgeOptions) (*PurgeResult, error) {
+	plan, sizes, err := s.scanForPurge(popts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PurgeResult{ClientSide: true, Sequences: []uint64{}}
+
+	for i, seq := range plan.Sequences {
+		err = s.DeleteMessage(int(seq))
+		if errors.Is(err, api.ErrMessageNotFound) {
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("deleting message %d failed after removing %d messages: %w", seq, res.Messages, err)
+		}
+
+		res.Messages++
+		res.Bytes += sizes[i]
+		res.Sequences = append(res.Sequences, seq)
+	}
+
+	return res, nil
+}
+
+// scanForPurge finds the messages a purge would remove and the storage used by each
+func (s *Stream) scanForPurge(popts *purgeOptions) (*PurgeResult, []uint64, error) {
+	plan := &PurgeResult{DryRun: popts.dryRun, ClientSide: true, Sequences: []uint64{}}
+	var sizes []uint64
+
+	var ropts []StreamReaderOption
+	if popts.req.Subject != "" {
+		ropts = append(ropts, ReaderFilter(popts.req.Subject))
+	}
+
+	if popts.req.Sequence > 0 {
+		if popts.req.Sequence == 1 {
+			return plan, sizes, nil
+		}
+
+		ropts = append(ropts, ReaderEndSequence(popts.req.Sequence-1))
+	}
+
+	reader, err := s.Reader(popts.ctx, ropts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plan.Sequences = append(plan.Sequences, msg.Sequence)
+		sizes = append(sizes, s.storedMsgSize(msg.Subject, msg.Data))
+	}
+
+	if keep := int(popts.req.Keep); keep > 0 {
+		if keep > len(plan.Sequences) {
+			keep = len(plan.Sequences)
+		}
+
+		plan.Sequences = plan.Sequences[:len(plan.Sequences)-keep]
+		sizes = sizes[:len(sizes)-keep]
+	}
+
+	plan.Messages = uint64(len(plan.Sequences))
+	for _, size := range sizes {
+		plan.Bytes += size
+	}
+
+	return plan, sizes, nil
+}
+
+// storedMsgSize is how the server accounts for the size of a message in Stream state
+func (s *Stream) storedMsgSize(subject string, data []byte) uint64 {
+	if s.Storage() == api.FileStorage {
+		// record length, sequence, timestamp, subject length and hash
+		return uint64(4 + 16 + 2 + len(subject) + len(data) + 8)
+	}
+
+	// sequence and timestamp
+	return uint64(len(subject) + len(data) + 16)
+}
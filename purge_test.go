@@ -0,0 +1,98 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func setupPurgeTest(t *testing.T, storage jsm.StreamOption) *jsm.Stream {
+	t.Helper()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), storage)
+	checkErr(t, err, "create failed")
+
+	for i := 1; i <= 10; i++ {
+		subj := "ORDERS.new"
+		if i%2 == 0 {
+			subj = "ORDERS.processed"
+		}
+
+		_, err = stream.Publish(subj, []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+	}
+
+	return stream
+}
+
+func TestStream_PurgeMessages(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for _, storage := range []jsm.StreamOption{jsm.MemoryStorage(), jsm.FileStorage()} {
+		stream := setupPurgeTest(t, storage)
+
+		before, err := stream.State()
+		checkErr(t, err, "state failed")
+
+		dry, err := stream.PurgeMessages(jsm.PurgeSubject("ORDERS.new"), jsm.PurgeKeep(1), jsm.PurgeDryRun())
+		checkErr(t, err, "dry run failed")
+		if !dry.DryRun || !reflect.DeepEqual(dry.Sequences, []uint64{1, 3, 5, 7}) {
+			t.Fatalf("unexpected dry run result: %+v", dry)
+		}
+
+		state, err := stream.State()
+		checkErr(t, err, "state failed")
+		if state.Msgs != 10 {
+			t.Fatalf("dry run removed messages: %+v", state)
+		}
+
+		res, err := stream.PurgeMessages(jsm.PurgeSubject("ORDERS.new"), jsm.PurgeKeep(1))
+		checkErr(t, err, "purge failed")
+		if res.DryRun || res.Messages != 4 || res.Bytes != dry.Bytes {
+			t.Fatalf("unexpected purge result: %+v", res)
+		}
+
+		state, err = stream.State()
+		checkErr(t, err, "state failed")
+		if state.Msgs != 6 || before.Bytes-state.Bytes != res.Bytes {
+			t.Fatalf("unexpected state after purge: %+v, removed %d bytes", state, res.Bytes)
+		}
+
+		res, err = stream.PurgeMessages(jsm.PurgeUpToSequence(6))
+		checkErr(t, err, "purge failed")
+		if res.Messages != 2 || !reflect.DeepEqual(res.Sequences, []uint64{2, 4}) {
+			t.Fatalf("unexpected purge result: %+v", res)
+		}
+
+		res, err = stream.PurgeMessages(jsm.PurgeUpToSequence(1))
+		checkErr(t, err, "purge failed")
+		if res.Messages != 0 {
+			t.Fatalf("unexpected purge result: %+v", res)
+		}
+
+		res, err = stream.PurgeMessages()
+		checkErr(t, err, "purge failed")
+		if res.Messages != 4 || res.ClientSide {
+			t.Fatalf("unexpected purge result: %+v", res)
+		}
+
+		checkErr(t, stream.Delete(), "delete failed")
+	}
+}
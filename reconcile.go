@@ -0,0 +1,726 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// DesiredState is a declarative description of the Streams, their Consumers and Stream Templates that should exist
+//
+// Streams and Consumers are described using the api.StreamConfig and api.ConsumerConfig JSON shapes, fields that are
+// not set default to those in DefaultStream and DefaultConsumer
+type DesiredState struct {
+	Streams   []DesiredStream   `json:"streams,omitempty"`
+	Templates []DesiredTemplate `json:"templates,omitempty"`
+	// Protected lists Streams, Stream Templates and - as STREAM:CONSUMER - Consumers that will never be deleted or recreated
+	Protected []string `json:"protected,omitempty"`
+}
+
+// DesiredStream is the desired configuration of a Stream and its Consumers
+type DesiredStream struct {
+	api.StreamConfig
+	Consumers []DesiredConsumer `json:"consumers,omitempty"`
+}
+
+// DesiredConsumer is the desired configuration of a durable Consumer
+type DesiredConsumer struct {
+	api.ConsumerConfig
+	// Protected consumers are never recreated to apply configuration changes
+	Protected bool `json:"protected,omitempty"`
+}
+
+// DesiredTemplate is the desired configuration of a Stream Template
+type DesiredTemplate struct {
+	api.StreamTemplateConfig
+}
+
+// UnmarshalJSON implements json.Unmarshaler setting defaults from DefaultStream
+func (s *DesiredStream) UnmarshalJSON(data []byte) error {
+	type desired DesiredStream
+	d := desired{StreamConfig: DefaultStream}
+
+	err := json.Unmarshal(data, &d)
+	if err != nil {
+		return err
+	}
+
+	*s = DesiredStream(d)
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler setting defaults from DefaultConsumer
+func (c *DesiredConsumer) UnmarshalJSON(data []byte) error {
+	type desired DesiredConsumer
+	d := desired{ConsumerConfig: DefaultConsumer}
+
+	err := json.Unmarshal(data, &d)
+	if err != nil {
+		return err
+	}
+
+	// the server stores unlimited deliveries as -1
+	if d.MaxDeliver == 0 {
+		d.MaxDeliver = -1
+	}
+
+	*c = DesiredConsumer(d)
+
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler setting stream defaults from DefaultStream
+func (t *DesiredTemplate) UnmarshalJSON(data []byte) error {
+	type desired DesiredTemplate
+	cfg := DefaultStream
+	d := desired{StreamTemplateConfig: api.StreamTemplateConfig{Config: &cfg}}
+
+	err := json.Unmarshal(data, &d)
+	if err != nil {
+		return err
+	}
+
+	*t = DesiredTemplate(d)
+
+	return nil
+}
+
+// ParseDesiredState parses a YAML or JSON desired state document and validates it
+func ParseDesiredState(data []byte) (*DesiredState, error) {
+	state := &DesiredState{}
+
+	err := yaml.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+
+	err = state.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// LoadDesiredState reads a YAML or JSON desired state document from file
+func LoadDesiredState(file string) (*DesiredState, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseDesiredState(data)
+}
+
+// Validate checks all configurations in the desired state
+func (d *DesiredState) Validate() error {
+	var errs []string
+	streams := map[string]bool{}
+
+	for _, s := range d.Streams {
+		if streams[s.Name] {
+			errs = append(errs, fmt.Sprintf("stream %s is listed more than once", s.Name))
+		}
+		streams[s.Name] = true
+
+		if s.Template != "" {
+			errs = append(errs, fmt.Sprintf("stream %s can not be template managed", s.Name))
+		}
+
+		ok, verrs := s.StreamConfig.Validate()
+		if !ok {
+			errs = append(errs, fmt.Sprintf("stream %s: %s", s.Name, strings.Join(verrs, ", ")))
+		}
+
+		consumers := map[string]bool{}
+		for _, c := range s.Consumers {
+			if c.Durable == "" {
+				errs = append(errs, fmt.Sprintf("stream %s has a consumer without a durable name", s.Name))
+				continue
+			}
+
+			if consumers[c.Durable] {
+				errs = append(errs, fmt.Sprintf("consumer %s > %s is listed more than once", s.Name, c.Durable))
+			}
+			consumers[c.Durable] = true
+
+			ok, verrs := c.ConsumerConfig.Validate()
+			if !ok {
+				errs = append(errs, fmt.Sprintf("consumer %s > %s: %s", s.Name, c.Durable, strings.Join(verrs, ", ")))
+			}
+		}
+	}
+
+	for _, t := range d.Templates {
+		ok, verrs := t.StreamTemplateConfig.Validate()
+		if !ok {
+			errs = append(errs, fmt.Sprintf("stream template %s: %s", t.Name, strings.Join(verrs, ", ")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return validationError(errs)
+	}
+
+	return nil
+}
+
+func (d *DesiredState) isProtected(name string) bool {
+	for _, p := range d.Protected {
+		if p == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReconcileAction is what the reconciler will do to a single Stream, Stream Template or Consumer
+type ReconcileAction string
+
+const (
+	ReconcileCreate    ReconcileAction = "create"
+	ReconcileUpdate    ReconcileAction = "update"
+	ReconcileRecreate  ReconcileAction = "recreate"
+	ReconcileDelete    ReconcileAction = "delete"
+	ReconcileUnchanged ReconcileAction = "unchanged"
+	ReconcileConflict  ReconcileAction = "conflict"
+)
+
+// ReconcileItem is a planned change to a single Stream, Stream Template or Consumer
+type ReconcileItem struct {
+	// Type is the kind of item, one of stream, stream_template or consumer
+	Type string `json:"type"`
+	// Stream is the Stream a consumer belongs to
+	Stream  string          `json:"stream,omitempty"`
+	Name    string          `json:"name"`
+	Action  ReconcileAction `json:"action"`
+	Changes []FieldChange   `json:"changes,omitempty"`
+	// Reason explains conflicts
+	Reason string `json:"reason,omitempty"`
+
+	// StreamConfig, ConsumerConfig and TemplateConfig are the desired configuration applied by creates and updates
+	StreamConfig   *api.StreamConfig         `json:"stream_config,omitempty"`
+	ConsumerConfig *api.ConsumerConfig       `json:"consumer_config,omitempty"`
+	TemplateConfig *api.StreamTemplateConfig `json:"template_config,omitempty"`
+}
+
+// ReconcilePlan is the list of changes needed to bring the server to the desired state
+type ReconcilePlan struct {
+	Items []ReconcileItem `json:"items"`
+}
+
+// HasConflicts determines if the plan holds changes that can not be made
+func (p *ReconcilePlan) HasConflicts() bool {
+	return len(p.ItemsWithAction(ReconcileConflict)) > 0
+}
+
+// HasChanges determines if the plan will change anything on the server
+func (p *ReconcilePlan) HasChanges() bool {
+	for _, i := range p.Items {
+		if i.Action != ReconcileUnchanged && i.Action != ReconcileConflict {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ItemsWithAction is all items in the plan with a specific action
+func (p *ReconcilePlan) ItemsWithAction(action ReconcileAction) []ReconcileItem {
+	var items []ReconcileItem
+	for _, i := range p.Items {
+		if i.Action == action {
+			items = append(items, i)
+		}
+	}
+
+	return items
+}
+
+// ReconcileOption configures the reconciler
+type ReconcileOption func(o *reconcileOptions)
+
+type reconcileOptions struct {
+	ropts    []RequestOption
+	prune    bool
+	recreate bool
+	log      Logger
+}
+
+func newReconcileOptions(opts ...ReconcileOption) *reconcileOptions {
+	ropts := &reconcileOptions{log: discardLogger{}}
+	for _, opt := range opts {
+		opt(ropts)
+	}
+
+	return ropts
+}
+
+// ReconcileConnection sets the connection related options used to communicate with JetStream
+func ReconcileConnection(opts ...RequestOption) ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.ropts = append(o.ropts, opts...)
+	}
+}
+
+// ReconcilePrune deletes Streams, durable Consumers and Stream Templates that are not in the desired state
+func ReconcilePrune() ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.prune = true
+	}
+}
+
+// ReconcileRecreateConsumers allows Consumers with configuration changes to be deleted and created again using
+// Consumer.RecreateWithConfiguration, the recreated Consumer starts after the ack floor of the old one so
+// unacknowledged messages are delivered again. Without it these changes are conflicts
+func ReconcileRecreateConsumers() ReconcileOption {
+	return func(o *reconcileOptions) {
+		o.recreate = true
+	}
+}
+
+// ReconcileLogger sets a logger to report on changes being made, nothing is logged by default
+func ReconcileLogger(l Logger) ReconcileOption {
+	return func(o *reconcileOptions) {
+		if l == nil {
+			l = discardLogger{}
+		}
+
+		o.log = l
+	}
+}
+
+// PlanReconcile compares the desired state to the server and reports the changes Reconcile would make
+func PlanReconcile(desired *DesiredState, opts ...ReconcileOption) (*ReconcilePlan, error) {
+	ropts := newReconcileOptions(opts...)
+	plan := &ReconcilePlan{Items: []ReconcileItem{}}
+
+	liveTemplates, err := StreamTemplateNames(ropts.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	wantTemplates := map[string]bool{}
+	for i := range desired.Templates {
+		t := desired.Templates[i]
+		wantTemplates[t.Name] = true
+
+		item, err := planTemplateReconcile(t, contains(liveTemplates, t.Name), ropts)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Items = append(plan.Items, *item)
+	}
+
+	liveStreams, err := StreamNames(ropts.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	wantStreams := map[string]bool{}
+	for _, s := range desired.Streams {
+		wantStreams[s.Name] = true
+
+		items, err := planStreamReconcile(desired, s, contains(liveStreams, s.Name), ropts)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Items = append(plan.Items, items...)
+	}
+
+	if !ropts.prune {
+		return plan, nil
+	}
+
+	for _, name := range liveStreams {
+		if wantStreams[name] || desired.isProtected(name) {
+			continue
+		}
+
+		stream, err := LoadStream(name, ropts.ropts...)
+		if err != nil {
+			return nil, err
+		}
+
+		// these are removed with their template
+		if stream.IsTemplateManaged() {
+			continue
+		}
+
+		plan.Items = append(plan.Items, ReconcileItem{Type: "stream", Stream: name, Name: name, Action: ReconcileDelete})
+	}
+
+	for _, name := range liveTemplates {
+		if wantTemplates[name] || desired.isProtected(name) {
+			continue
+		}
+
+		plan.Items = append(plan.Items, ReconcileItem{Type: "stream_template", Name: name, Action: ReconcileDelete})
+	}
+
+	return plan, nil
+}
+
+// Reconcile makes the changes needed to bring the server to the desired state, nothing is changed when the plan has conflicts
+func Reconcile(desired *DesiredState, opts ...ReconcileOption) (*ReconcilePlan, error) {
+	plan, err := PlanReconcile(desired, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ApplyReconcilePlan(plan, opts...)
+	if err != nil {
+		return plan, err
+	}
+
+	return plan, nil
+}
+
+// ApplyReconcilePlan makes the changes in a plan made by PlanReconcile, nothing is changed when the plan has conflicts
+func ApplyReconcilePlan(plan *ReconcilePlan, opts ...ReconcileOption) error {
+	ropts := newReconcileOptions(opts...)
+
+	if plan.HasConflicts() {
+		var conflicts []string
+		for _, i := range plan.ItemsWithAction(ReconcileConflict) {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s: %s", i.Type, i.Name, i.Reason))
+		}
+
+		return fmt.Errorf("plan has conflicts: %s", strings.Join(conflicts, ", "))
+	}
+
+	// deletes are done first so that subjects of deleted streams are available
+	for _, itype := range []string{"consumer", "stream", "stream_template"} {
+		for _, i := range plan.Items {
+			if i.Type != itype || i.Action != ReconcileDelete {
+				continue
+			}
+
+			err := reconcileDelete(i, ropts)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, i := range plan.Items {
+		var err error
+
+		switch i.Action {
+		case ReconcileCreate:
+			err = reconcileCreate(i, ropts)
+		case ReconcileRecreate:
+			err = reconcileRecreate(i, ropts)
+		case ReconcileUpdate:
+			err = reconcileUpdate(i, ropts)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func reconcileDelete(i ReconcileItem, ropts *reconcileOptions) error {
+	switch i.Type {
+	case "consumer":
+		ropts.log.Infof("Deleting Consumer %s > %s", i.Stream, i.Name)
+		consumer, err := LoadConsumer(i.Stream, i.Name, ropts.ropts...)
+		if err != nil {
+			return err
+		}
+
+		return consumer.Delete()
+
+	case "stream":
+		ropts.log.Infof("Deleting Stream %s", i.Name)
+		stream, err := LoadStream(i.Name, ropts.ropts...)
+		if err != nil {
+			return err
+		}
+
+		return stream.Delete()
+
+	case "stream_template":
+		ropts.log.Infof("Deleting Stream Template %s", i.Name)
+		template, err := LoadStreamTemplate(i.Name, ropts.ropts...)
+		if err != nil {
+			return err
+		}
+
+		return template.Delete()
+	}
+
+	return fmt.Errorf("unknown item type %q", i.Type)
+}
+
+func reconcileCreate(i ReconcileItem, ropts *reconcileOptions) (err error) {
+	err = i.checkConfig()
+	if err != nil {
+		return err
+	}
+
+	switch i.Type {
+	case "consumer":
+		ropts.log.Infof("Creating Consumer %s > %s", i.Stream, i.Name)
+		_, err = NewConsumerFromDefault(i.Stream, *i.ConsumerConfig, ConsumerConnection(ropts.ropts...))
+
+	case "stream":
+		ropts.log.Infof("Creating Stream %s", i.Name)
+		_, err = NewStreamFromDefault(i.Name, *i.StreamConfig, StreamConnection(ropts.ropts...))
+
+	case "stream_template":
+		ropts.log.Infof("Creating Stream Template %s", i.Name)
+		cfg := *i.TemplateConfig.Config
+		cfg.Name = ""
+		_, err = NewStreamTemplate(i.Name, i.TemplateConfig.MaxStreams, cfg, StreamConnection(ropts.ropts...))
+
+	default:
+		err = fmt.Errorf("unknown item type %q", i.Type)
+	}
+
+	return err
+}
+
+func reconcileRecreate(i ReconcileItem, ropts *reconcileOptions) error {
+	if i.Type != "consumer" {
+		return fmt.Errorf("%s %s can not be recreated", i.Type, i.Name)
+	}
+
+	err := i.checkConfig()
+	if err != nil {
+		return err
+	}
+
+	ropts.log.Infof("Recreating Consumer %s > %s", i.Stream, i.Name)
+	consumer, err := LoadConsumer(i.Stream, i.Name, ropts.ropts...)
+	if err != nil {
+		return err
+	}
+
+	return consumer.RecreateWithConfiguration(*i.ConsumerConfig)
+}
+
+func reconcileUpdate(i ReconcileItem, ropts *reconcileOptions) error {
+	if i.Type != "stream" {
+		return fmt.Errorf("%s %s can not be updated", i.Type, i.Name)
+	}
+
+	err := i.checkConfig()
+	if err != nil {
+		return err
+	}
+
+	ropts.log.Infof("Updating Stream %s", i.Name)
+	stream, err := LoadStream(i.Name, ropts.ropts...)
+	if err != nil {
+		return err
+	}
+
+	return stream.UpdateConfiguration(*i.StreamConfig)
+}
+
+// checkConfig ensures the desired configuration needed to create or update the item is present, plans that were
+// edited or stored without it can not be applied
+func (i ReconcileItem) checkConfig() error {
+	var missing bool
+
+	switch i.Type {
+	case "consumer":
+		missing = i.ConsumerConfig == nil
+	case "stream":
+		missing = i.StreamConfig == nil
+	case "stream_template":
+		missing = i.TemplateConfig == nil || i.TemplateConfig.Config == nil
+	default:
+		return fmt.Errorf("unknown item type %q", i.Type)
+	}
+
+	if missing {
+		return fmt.Errorf("%s %s can not be applied, the plan has no desired configuration for it", i.Type, i.Name)
+	}
+
+	return nil
+}
+
+func planTemplateReconcile(desired DesiredTemplate, live bool, ropts *reconcileOptions) (*ReconcileItem, error) {
+	cfg := desired.StreamTemplateConfig
+	item := &ReconcileItem{Type: "stream_template", Name: cfg.Name, TemplateConfig: &cfg}
+
+	if !live {
+		item.Action = ReconcileCreate
+		return item, nil
+	}
+
+	template, err := LoadStreamTemplate(cfg.Name, ropts.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server sets the stream name to the template name
+	want := cfg
+	wantStream := *cfg.Config
+	wantStream.Name = template.Configuration().Config.Name
+	want.Config = &wantStream
+
+	item.Changes = diffConfigs(template.Configuration(), want)
+	if len(item.Changes) == 0 {
+		item.Action = ReconcileUnchanged
+		return item, nil
+	}
+
+	item.Action = ReconcileConflict
+	item.Reason = "stream templates can not be updated"
+
+	return item, nil
+}
+
+func planStreamReconcile(desired *DesiredState, s DesiredStream, live bool, ropts *reconcileOptions) ([]ReconcileItem, error) {
+	cfg := s.StreamConfig
+	item := ReconcileItem{Type: "stream", Stream: cfg.Name, Name: cfg.Name, StreamConfig: &cfg}
+	var liveConsumers []string
+
+	if live {
+		stream, err := LoadStream(cfg.Name, ropts.ropts...)
+		if err != nil {
+			return nil, err
+		}
+
+		diff := DiffStreamConfig(stream.Configuration(), cfg)
+		item.Changes = diff.FieldChanges()
+		immutable := diff.WithClass(ChangeForbidden).Fields()
+
+		switch {
+		case len(item.Changes) == 0:
+			item.Action = ReconcileUnchanged
+		case stream.IsTemplateManaged():
+			item.Action = ReconcileConflict
+			item.Reason = fmt.Sprintf("the stream is managed by Stream Template %s", stream.Template())
+		case len(immutable) > 0:
+			item.Action = ReconcileConflict
+			item.Reason = fmt.Sprintf("%s can not be changed", strings.Join(immutable, ", "))
+		default:
+			item.Action = ReconcileUpdate
+		}
+
+		liveConsumers, err = stream.ConsumerNames()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		item.Action = ReconcileCreate
+	}
+
+	items := []ReconcileItem{item}
+	wantConsumers := map[string]bool{}
+
+	for _, c := range s.Consumers {
+		wantConsumers[c.Durable] = true
+		ccfg := c.ConsumerConfig
+		citem := ReconcileItem{Type: "consumer", Stream: cfg.Name, Name: c.Durable, ConsumerConfig: &ccfg}
+
+		if !contains(liveConsumers, c.Durable) {
+			citem.Action = ReconcileCreate
+			items = append(items, citem)
+			continue
+		}
+
+		consumer, err := LoadConsumer(cfg.Name, c.Durable, ropts.ropts...)
+		if err != nil {
+			return nil, err
+		}
+
+		citem.Changes = consumerReconcileChanges(consumer.Configuration(), ccfg)
+
+		switch {
+		case len(citem.Changes) == 0:
+			citem.Action = ReconcileUnchanged
+		case c.Protected || desired.isProtected(cfg.Name+":"+c.Durable):
+			citem.Action = ReconcileConflict
+			citem.Reason = "consumers can not be updated and the consumer is protected from being recreated"
+		case !ropts.recreate:
+			citem.Action = ReconcileConflict
+			citem.Reason = "consumers can not be updated, recreating it requires ReconcileRecreateConsumers"
+		default:
+			citem.Action = ReconcileRecreate
+		}
+
+		items = append(items, citem)
+	}
+
+	if !ropts.prune || !live {
+		return items, nil
+	}
+
+	for _, name := range liveConsumers {
+		if wantConsumers[name] || desired.isProtected(cfg.Name+":"+name) {
+			continue
+		}
+
+		consumer, err := LoadConsumer(cfg.Name, name, ropts.ropts...)
+		if errors.Is(err, api.ErrConsumerNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if consumer.IsEphemeral() {
+			continue
+		}
+
+		items = append(items, ReconcileItem{Type: "consumer", Stream: cfg.Name, Name: name, Action: ReconcileDelete})
+	}
+
+	return items, nil
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// consumerReconcileChanges are the changes to an existing Consumer, where it starts only applies when a Consumer is
+// created and recreated Consumers start after the old ack floor so the start position fields are ignored
+func consumerReconcileChanges(current api.ConsumerConfig, desired api.ConsumerConfig) []FieldChange {
+	var changes []FieldChange
+	for _, c := range diffConfigs(current, desired) {
+		if contains([]string{"deliver_policy", "opt_start_seq", "opt_start_time"}, c.Field) {
+			continue
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes
+}
@@ -0,0 +1,250 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+const desiredStateYAML = `
+streams:
+  - name: ORDERS
+    subjects: ["ORDERS.*"]
+    storage: memory
+    consumers:
+      - durable_name: NEW
+        filter_subject: ORDERS.new
+      - durable_name: AUDIT
+        ack_wait: 60000000000
+        protected: true
+  - name: SHIPPED
+    subjects: ["SHIPPED.*"]
+    storage: memory
+    max_msgs: 1000
+templates:
+  - name: ARCHIVE
+    max_streams: 10
+    config:
+      subjects: ["ARCHIVE.*"]
+      storage: memory
+protected:
+  - KEEP
+`
+
+func planActions(t *testing.T, plan *jsm.ReconcilePlan) map[string]jsm.ReconcileAction {
+	t.Helper()
+
+	actions := map[string]jsm.ReconcileAction{}
+	for _, i := range plan.Items {
+		actions[i.Type+":"+i.Stream+":"+i.Name] = i.Action
+	}
+
+	return actions
+}
+
+func TestParseDesiredState(t *testing.T) {
+	state, err := jsm.ParseDesiredState([]byte(desiredStateYAML))
+	checkErr(t, err, "parse failed")
+
+	if len(state.Streams) != 2 || len(state.Templates) != 1 {
+		t.Fatalf("invalid state %+v", state)
+	}
+
+	orders := state.Streams[0]
+	if orders.Name != "ORDERS" || orders.Storage != api.MemoryStorage || orders.MaxAge != jsm.DefaultStream.MaxAge || orders.MaxMsgs != -1 {
+		t.Fatalf("invalid stream %+v", orders.StreamConfig)
+	}
+
+	audit := orders.Consumers[1]
+	if !audit.Protected || audit.AckWait.Seconds() != 60 || audit.AckPolicy != api.AckExplicit {
+		t.Fatalf("invalid consumer %+v", audit)
+	}
+
+	if state.Templates[0].Config.MaxMsgs != -1 || state.Templates[0].Config.Storage != api.MemoryStorage {
+		t.Fatalf("invalid template %+v", state.Templates[0].Config)
+	}
+
+	_, err = jsm.ParseDesiredState([]byte(`{"streams": [{"name": "X", "consumers": [{"ack_policy": "explicit"}]}]}`))
+	if !errors.Is(err, api.ErrInvalidConfiguration) {
+		t.Fatalf("expected a validation error got %v", err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	orders, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.MaxMessages(10))
+	checkErr(t, err, "create failed")
+	_, err = orders.NewConsumer(jsm.DurableName("OLD"))
+	checkErr(t, err, "create failed")
+	_, err = jsm.NewStream("STALE", jsm.Subjects("STALE.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+	_, err = jsm.NewStream("KEEP", jsm.Subjects("KEEP.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	state, err := jsm.ParseDesiredState([]byte(desiredStateYAML))
+	checkErr(t, err, "parse failed")
+
+	plan, err := jsm.Reconcile(state)
+	checkErr(t, err, "reconcile failed")
+
+	expected := map[string]jsm.ReconcileAction{
+		"stream_template::ARCHIVE": jsm.ReconcileCreate,
+		"stream:ORDERS:ORDERS":     jsm.ReconcileUpdate,
+		"consumer:ORDERS:NEW":      jsm.ReconcileCreate,
+		"consumer:ORDERS:AUDIT":    jsm.ReconcileCreate,
+		"stream:SHIPPED:SHIPPED":   jsm.ReconcileCreate,
+	}
+
+	actions := planActions(t, plan)
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, actions)
+	}
+	for k, v := range expected {
+		if actions[k] != v {
+			t.Fatalf("expected %s to be %s got %s", k, v, actions[k])
+		}
+	}
+
+	orders, err = jsm.LoadStream("ORDERS")
+	checkErr(t, err, "load failed")
+	if orders.MaxMsgs() != -1 {
+		t.Fatalf("ORDERS was not updated")
+	}
+
+	// applying the same state again changes nothing
+	plan, err = jsm.PlanReconcile(state)
+	checkErr(t, err, "plan failed")
+	if plan.HasChanges() {
+		t.Fatalf("expected no changes got %+v", plan.Items)
+	}
+
+	// prune removes everything not in the state except protected streams
+	plan, err = jsm.Reconcile(state, jsm.ReconcilePrune())
+	checkErr(t, err, "reconcile failed")
+
+	deletes := plan.ItemsWithAction(jsm.ReconcileDelete)
+	if len(deletes) != 2 {
+		t.Fatalf("expected 2 deletes got %+v", deletes)
+	}
+
+	names, err := jsm.StreamNames()
+	checkErr(t, err, "names failed")
+	if len(names) != 3 || names[0] != "KEEP" || names[1] != "ORDERS" || names[2] != "SHIPPED" {
+		t.Fatalf("unexpected streams %v", names)
+	}
+
+	known, err := jsm.IsKnownConsumer("ORDERS", "OLD")
+	checkErr(t, err, "known failed")
+	if known {
+		t.Fatalf("OLD was not pruned")
+	}
+
+	// consumer changes are conflicts unless recreating them is allowed
+	state.Streams[0].Consumers[0].FilterSubject = "ORDERS.received"
+	plan, err = jsm.PlanReconcile(state)
+	checkErr(t, err, "plan failed")
+
+	conflicts := plan.ItemsWithAction(jsm.ReconcileConflict)
+	if len(conflicts) != 1 || conflicts[0].Name != "NEW" {
+		t.Fatalf("expected NEW to conflict got %+v", plan.Items)
+	}
+
+	// consumers are recreated after their ack floor when allowed unless protected
+	for i := 0; i < 3; i++ {
+		_, err = jsm.Publish("ORDERS.new", []byte("order"))
+		checkErr(t, err, "publish failed")
+	}
+
+	for i := 0; i < 2; i++ {
+		msg, err := jsm.NextMsg("ORDERS", "NEW")
+		checkErr(t, err, "next failed")
+		checkErr(t, msg.Respond(nil), "ack failed")
+	}
+
+	plan, err = jsm.Reconcile(state, jsm.ReconcileRecreateConsumers())
+	checkErr(t, err, "reconcile failed")
+
+	recreated := plan.ItemsWithAction(jsm.ReconcileRecreate)
+	if len(recreated) != 1 || recreated[0].Name != "NEW" || recreated[0].Changes[0].Field != "filter_subject" {
+		t.Fatalf("expected NEW to be recreated got %+v", plan.Items)
+	}
+
+	consumer, err := jsm.LoadConsumer("ORDERS", "NEW")
+	checkErr(t, err, "load failed")
+	if consumer.FilterSubject() != "ORDERS.received" || consumer.StartSequence() != 3 {
+		t.Fatalf("expected NEW to start after its ack floor: %+v", consumer.Configuration())
+	}
+
+	// the recreated start position is not a change
+	plan, err = jsm.PlanReconcile(state, jsm.ReconcileRecreateConsumers())
+	checkErr(t, err, "plan failed")
+	if plan.HasChanges() {
+		t.Fatalf("expected no changes got %+v", plan.Items)
+	}
+
+	state.Streams[0].Consumers[1].MaxDeliver = 10
+	state.Streams[0].MaxMsgs = 100
+	plan, err = jsm.Reconcile(state, jsm.ReconcileRecreateConsumers())
+	if err == nil || !plan.HasConflicts() {
+		t.Fatalf("expected a conflict for AUDIT")
+	}
+
+	orders, err = jsm.LoadStream("ORDERS")
+	checkErr(t, err, "load failed")
+	if orders.MaxMsgs() != -1 {
+		t.Fatalf("ORDERS was updated despite the conflict")
+	}
+}
+
+func TestApplyReconcilePlan_Serialized(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	state, err := jsm.ParseDesiredState([]byte(desiredStateYAML))
+	checkErr(t, err, "parse failed")
+
+	plan, err := jsm.PlanReconcile(state)
+	checkErr(t, err, "plan failed")
+
+	// plans can be stored for approval and applied later
+	j, err := json.Marshal(plan)
+	checkErr(t, err, "marshal failed")
+
+	approved := &jsm.ReconcilePlan{}
+	checkErr(t, json.Unmarshal(j, approved), "unmarshal failed")
+
+	checkErr(t, jsm.ApplyReconcilePlan(approved), "apply failed")
+
+	plan, err = jsm.PlanReconcile(state)
+	checkErr(t, err, "plan failed")
+	if plan.HasChanges() {
+		t.Fatalf("expected no changes after applying the approved plan got %+v", plan.Items)
+	}
+
+	incomplete := &jsm.ReconcilePlan{Items: []jsm.ReconcileItem{{Type: "stream", Name: "OTHER", Action: jsm.ReconcileCreate}}}
+	err = jsm.ApplyReconcilePlan(incomplete)
+	if err == nil || !strings.Contains(err.Error(), "no desired configuration") {
+		t.Fatalf("expected a missing configuration error got %v", err)
+	}
+}
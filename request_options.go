@@ -16,18 +16,27 @@ package jsm
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
 )
 
 // RequestOption is a option to configure the NATS related options
 type RequestOption func(o *reqoptions)
 
 type reqoptions struct {
-	nc      *nats.Conn
-	timeout time.Duration
-	ctx     context.Context
+	nc        *nats.Conn
+	timeout   time.Duration
+	ctx       context.Context
+	traceCtx  context.Context
+	apiPrefix string
+	retry     *RetryPolicy
+	observers []RequestObserver
+	msgID     string
+	dedup     *DedupCache
 }
 
 func dfltreqoptions() *reqoptions {
@@ -51,6 +60,14 @@ func newreqoptions(opts ...RequestOption) (*reqoptions, error) {
 	return ropts, nil
 }
 
+// clone copies the options so options applied to the copy do not change o
+func (o *reqoptions) clone() *reqoptions {
+	c := *o
+	c.observers = append([]RequestObserver{}, o.observers...)
+
+	return &c
+}
+
 // WithConnection sets the connection to use
 func WithConnection(nc *nats.Conn) RequestOption {
 	return func(o *reqoptions) {
@@ -71,3 +88,43 @@ func WithContext(ctx context.Context) RequestOption {
 		o.ctx = ctx
 	}
 }
+
+// WithAPIPrefix replaces the $JS prefix of all JetStream API, advisory and metric subjects with p, use this to manage
+// a JetStream imported from another account or reached over a leafnode, for example JS.acctB.$JS
+func WithAPIPrefix(p string) RequestOption {
+	return func(o *reqoptions) {
+		o.apiPrefix = strings.TrimSuffix(p, ".")
+	}
+}
+
+// context is the context set using WithContext or one bound by the timeout
+func (o *reqoptions) context() (context.Context, context.CancelFunc) {
+	// observers can return a context derived from ctx, like one holding a span, that the request is made with
+	ctx := o.ctx
+	if o.traceCtx != nil {
+		ctx = o.traceCtx
+	}
+
+	if o.ctx != nil {
+		return ctx, func() {}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// apiSubject rewrites subj from the default $JS prefix to the configured API prefix
+func (o *reqoptions) apiSubject(subj string) string {
+	if o == nil || o.apiPrefix == "" || o.apiPrefix == api.JetStreamAPIPrefix {
+		return subj
+	}
+
+	if !strings.HasPrefix(subj, api.JetStreamAPIPrefix+".") {
+		return subj
+	}
+
+	return o.apiPrefix + strings.TrimPrefix(subj, api.JetStreamAPIPrefix)
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+// proxies requests on prefix.$JS.> to $JS.> like an account import would
+func startAPIProxy(t *testing.T, nc *nats.Conn, prefix string) {
+	t.Helper()
+
+	_, err := nc.Subscribe(prefix+".$JS.>", func(m *nats.Msg) {
+		res, err := nc.Request(strings.TrimPrefix(m.Subject, prefix+"."), m.Data, time.Second)
+		if err != nil {
+			return
+		}
+
+		m.Respond(res.Data)
+	})
+	checkErr(t, err, "proxy subscribe failed")
+}
+
+func TestWithAPIPrefix(t *testing.T) {
+	srv, nc := startIsolatedJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	startAPIProxy(t, nc, "JS.acctB")
+
+	mgr, err := jsm.New(nc, jsm.WithAPIPrefix("JS.acctB.$JS"), jsm.WithTimeout(2*time.Second))
+	checkErr(t, err, "manager failed")
+
+	stream, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	if stream.AdvisorySubject() != "JS.acctB.$JS.EVENT.ADVISORY.*.ORDERS.*" {
+		t.Fatalf("unexpected advisory subject %q", stream.AdvisorySubject())
+	}
+
+	if stream.MetricSubject() != "JS.acctB.$JS.EVENT.METRIC.*.ORDERS.*" {
+		t.Fatalf("unexpected metric subject %q", stream.MetricSubject())
+	}
+
+	consumer, err := stream.NewConsumerFromDefault(jsm.SampledDefaultConsumer, jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	if consumer.NextSubject() != "JS.acctB.$JS.STREAM.ORDERS.CONSUMER.NEW.NEXT" {
+		t.Fatalf("unexpected next subject %q", consumer.NextSubject())
+	}
+
+	if consumer.AckSampleSubject() != "JS.acctB.$JS.EVENT.METRIC.CONSUMER_ACK.ORDERS.NEW" {
+		t.Fatalf("unexpected ack sample subject %q", consumer.AckSampleSubject())
+	}
+
+	if consumer.AdvisorySubject() != "JS.acctB.$JS.EVENT.ADVISORY.*.ORDERS.NEW" {
+		t.Fatalf("unexpected advisory subject %q", consumer.AdvisorySubject())
+	}
+
+	_, err = nc.Request("ORDERS.new", []byte("hello"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	msg, err := consumer.NextMsg()
+	checkErr(t, err, "next failed")
+	if string(msg.Data) != "hello" {
+		t.Fatalf("expected hello got %q", msg.Data)
+	}
+
+	// without the proxy prefix nothing answers so this would fail if any request escaped the prefix
+	names, err := mgr.ConsumerNames("ORDERS")
+	checkErr(t, err, "names failed")
+	if len(names) != 1 || names[0] != "NEW" {
+		t.Fatalf("expected [NEW] got %v", names)
+	}
+
+	_, err = jsm.StreamNames(jsm.WithConnection(nc), jsm.WithAPIPrefix("OTHER.$JS"), jsm.WithTimeout(100*time.Millisecond))
+	if err == nil {
+		t.Fatalf("expected a timeout on an unanswered prefix")
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// RestoreAction is what a restore would do to a single Stream, Stream Template or Consumer
+type RestoreAction string
+
+const (
+	// RestoreCreate means the item does not exist and will be created
+	RestoreCreate RestoreAction = "create"
+	// RestoreUpdate means the item exists and its configuration will be updated
+	RestoreUpdate RestoreAction = "update"
+	// RestoreUnchanged means the item exists with the same configuration
+	RestoreUnchanged RestoreAction = "unchanged"
+	// RestoreConflict means the item exists with a configuration that can not be restored, the restore will fail
+	RestoreConflict RestoreAction = "conflict"
+	// RestoreSkip means the item will not be restored
+	RestoreSkip RestoreAction = "skip"
+)
+
+// FieldChange is a difference in a single configuration field, Field is the JSON name of the field
+type FieldChange struct {
+	Field   string      `json:"field"`
+	Current interface{} `json:"current"`
+	Desired interface{} `json:"desired"`
+}
+
+// String implements fmt.Stringer
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %v => %v", c.Field, c.Current, c.Desired)
+}
+
+// RestorePlanItem describes what a restore would do to a single Stream, Stream Template or Consumer
+type RestorePlanItem struct {
+	// Type is the kind of item, one of stream, stream_template or consumer
+	Type string `json:"type"`
+	// Stream is the Stream a consumer belongs to
+	Stream  string        `json:"stream,omitempty"`
+	Name    string        `json:"name"`
+	Action  RestoreAction `json:"action"`
+	Changes []FieldChange `json:"changes,omitempty"`
+	// Reason explains conflicts and skipped items
+	Reason string `json:"reason,omitempty"`
+}
+
+// RestorePlan describes what RestoreJetStreamConfiguration would do without making any changes
+type RestorePlan struct {
+	Items []RestorePlanItem `json:"items"`
+}
+
+// HasConflicts determines if the restore would fail
+func (p *RestorePlan) HasConflicts() bool {
+	return len(p.ItemsWithAction(RestoreConflict)) > 0
+}
+
+// HasChanges determines if the restore would create or update anything
+func (p *RestorePlan) HasChanges() bool {
+	return len(p.ItemsWithAction(RestoreCreate)) > 0 || len(p.ItemsWithAction(RestoreUpdate)) > 0
+}
+
+// ItemsWithAction is all items in the plan with a specific action
+func (p *RestorePlan) ItemsWithAction(action RestoreAction) []RestorePlanItem {
+	var items []RestorePlanItem
+	for _, i := range p.Items {
+		if i.Action == action {
+			items = append(items, i)
+		}
+	}
+
+	return items
+}
+
+// stream fields the server does not allow to be changed by an update
+var immutableStreamFields = []string{"name", "max_consumers", "storage", "retention", "template_owner"}
+
+// PlanJetStreamConfigurationRestore compares a backup made by BackupJetStreamConfiguration against the live server
+// and reports what RestoreJetStreamConfiguration would do with the same update setting, nothing is changed on the server
+func PlanJetStreamConfigurationRestore(backupDir string, update bool, opts ...BackupOption) (*RestorePlan, error) {
+	bopts := newBackupOptions(opts...)
+
+	backups, err := loadBackups(backupDir, bopts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RestorePlan{Items: []RestorePlanItem{}}
+	streams := map[string]bool{}
+
+	for _, b := range backups {
+		if b.Type != "stream" {
+			continue
+		}
+
+		item, err := planStreamRestore(b, update, bopts)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.Action != RestoreSkip {
+			streams[item.Name] = true
+		}
+
+		plan.Items = append(plan.Items, *item)
+	}
+
+	for _, b := range backups {
+		if b.Type != "stream_template" {
+			continue
+		}
+
+		item, err := planStreamTemplateRestore(b, bopts)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Items = append(plan.Items, *item)
+	}
+
+	for _, b := range backups {
+		if b.Type != "consumer" {
+			continue
+		}
+
+		item, err := planConsumerRestore(b, streams, bopts)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Items = append(plan.Items, *item)
+	}
+
+	return plan, nil
+}
+
+func planStreamRestore(backup *BackupData, update bool, bopts *backupOptions) (*RestorePlanItem, error) {
+	sc := api.StreamConfig{}
+	err := json.Unmarshal(backup.Configuration, &sc)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &RestorePlanItem{Type: "stream", Stream: sc.Name, Name: sc.Name}
+
+	if sc.Template != "" {
+		item.Action = RestoreSkip
+		item.Reason = fmt.Sprintf("managed by Stream Template %s", sc.Template)
+		return item, nil
+	}
+
+	stream, err := LoadStream(sc.Name, bopts.ropts...)
+	if errors.Is(err, api.ErrStreamNotFound) {
+		item.Action = RestoreCreate
+		return item, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffStreamConfig(stream.Configuration(), sc)
+	item.Changes = diff.FieldChanges()
+	immutable := diff.WithClass(ChangeForbidden).Fields()
+
+	// the restore fails on any existing stream without update, even when it is unchanged
+	switch {
+	case !update:
+		item.Action = RestoreConflict
+		item.Reason = "stream exists and update was not specified"
+	case len(item.Changes) == 0:
+		item.Action = RestoreUnchanged
+	case stream.IsTemplateManaged():
+		item.Action = RestoreConflict
+		item.Reason = fmt.Sprintf("the existing stream is managed by Stream Template %s", stream.Template())
+	case len(immutable) > 0:
+		item.Action = RestoreConflict
+		item.Reason = fmt.Sprintf("%s can not be changed", strings.Join(immutable, ", "))
+	default:
+		item.Action = RestoreUpdate
+	}
+
+	return item, nil
+}
+
+func planStreamTemplateRestore(backup *BackupData, bopts *backupOptions) (*RestorePlanItem, error) {
+	tc := api.StreamTemplateConfig{}
+	err := json.Unmarshal(backup.Configuration, &tc)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &RestorePlanItem{Type: "stream_template", Name: tc.Name}
+
+	template, err := LoadStreamTemplate(tc.Name, bopts.ropts...)
+	if errors.Is(err, api.ErrTemplateNotFound) {
+		item.Action = RestoreCreate
+		return item, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the server rejects creating a template that exists even when it is unchanged
+	item.Changes = diffConfigs(template.Configuration(), tc)
+	item.Action = RestoreConflict
+	item.Reason = "stream template exists and stream templates can not be updated"
+
+	return item, nil
+}
+
+func planConsumerRestore(backup *BackupData, streams map[string]bool, bopts *backupOptions) (*RestorePlanItem, error) {
+	cc := ConsumerBackup{}
+	err := json.Unmarshal(backup.Configuration, &cc)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &RestorePlanItem{Type: "consumer", Stream: cc.Stream, Name: cc.Name}
+
+	known, err := IsKnownStream(cc.Stream, bopts.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !known {
+		if streams[cc.Stream] {
+			item.Action = RestoreCreate
+			return item, nil
+		}
+
+		item.Action = RestoreSkip
+		item.Reason = "stream does not exist, possibly managed by a Stream Template"
+		return item, nil
+	}
+
+	consumer, err := LoadConsumer(cc.Stream, cc.Name, bopts.ropts...)
+	if errors.Is(err, api.ErrConsumerNotFound) {
+		item.Action = RestoreCreate
+		return item, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item.Changes = diffConfigs(consumer.Configuration(), cc.Config)
+	if len(item.Changes) == 0 {
+		item.Action = RestoreUnchanged
+		return item, nil
+	}
+
+	item.Action = RestoreConflict
+	item.Reason = "consumers can not be updated"
+
+	return item, nil
+}
+
+// diffConfigs compares the top level fields of two structs of the same type, fields are named using their JSON names
+func diffConfigs(current interface{}, desired interface{}) []FieldChange {
+	cv := reflect.Indirect(reflect.ValueOf(current))
+	dv := reflect.Indirect(reflect.ValueOf(desired))
+	if cv.Type() != dv.Type() || cv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var changes []FieldChange
+	for i := 0; i < cv.NumField(); i++ {
+		f := cv.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		cf := cv.Field(i).Interface()
+		df := dv.Field(i).Interface()
+		if reflect.DeepEqual(cf, df) || (isEmptyValue(cv.Field(i)) && isEmptyValue(dv.Field(i))) {
+			continue
+		}
+
+		changes = append(changes, FieldChange{Field: jsonFieldName(f), Current: cf, Desired: df})
+	}
+
+	return changes
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+
+	return name
+}
+
+// considers nil and empty slices and maps equal
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
@@ -0,0 +1,176 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestPlanJetStreamConfigurationRestore(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	orders, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+	_, err = orders.NewConsumer(jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	shipped, err := jsm.NewStream("SHIPPED", jsm.Subjects("SHIPPED.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+	_, err = shipped.NewConsumer(jsm.DurableName("AUDIT"))
+	checkErr(t, err, "consumer create failed")
+
+	archive, err := jsm.NewStream("ARCHIVE", jsm.Subjects("ARCHIVE.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	dir := filepath.Join(td, "backup")
+	err = jsm.BackupJetStreamConfiguration(dir)
+	checkErr(t, err, "backup failed")
+
+	// SHIPPED and its consumer will be created, ORDERS updated and ARCHIVE conflicts
+	err = shipped.Delete()
+	checkErr(t, err, "delete failed")
+
+	cfg := orders.Configuration()
+	cfg.MaxAge = time.Hour
+	err = orders.UpdateConfiguration(cfg)
+	checkErr(t, err, "update failed")
+
+	err = archive.Delete()
+	checkErr(t, err, "delete failed")
+	_, err = jsm.NewStream("ARCHIVE", jsm.Subjects("ARCHIVE.*"), jsm.FileStorage())
+	checkErr(t, err, "create failed")
+
+	plan, err := jsm.PlanJetStreamConfigurationRestore(dir, true)
+	checkErr(t, err, "plan failed")
+
+	actions := map[string]jsm.RestoreAction{}
+	for _, i := range plan.Items {
+		actions[i.Type+":"+i.Stream+":"+i.Name] = i.Action
+	}
+
+	expected := map[string]jsm.RestoreAction{
+		"stream:ORDERS:ORDERS":   jsm.RestoreUpdate,
+		"stream:SHIPPED:SHIPPED": jsm.RestoreCreate,
+		"stream:ARCHIVE:ARCHIVE": jsm.RestoreConflict,
+		"consumer:ORDERS:NEW":    jsm.RestoreUnchanged,
+		"consumer:SHIPPED:AUDIT": jsm.RestoreCreate,
+	}
+
+	if len(actions) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, actions)
+	}
+
+	for k, v := range expected {
+		if actions[k] != v {
+			t.Fatalf("expected %s to be %s got %s", k, v, actions[k])
+		}
+	}
+
+	update := plan.ItemsWithAction(jsm.RestoreUpdate)[0]
+	if len(update.Changes) != 1 || update.Changes[0].Field != "max_age" || update.Changes[0].Desired != 365*24*time.Hour {
+		t.Fatalf("invalid changes %v", update.Changes)
+	}
+
+	conflict := plan.ItemsWithAction(jsm.RestoreConflict)[0]
+	if conflict.Reason != "storage can not be changed" {
+		t.Fatalf("invalid conflict reason %q", conflict.Reason)
+	}
+
+	if !plan.HasConflicts() || !plan.HasChanges() {
+		t.Fatalf("expected conflicts and changes")
+	}
+
+	// nothing was changed
+	known, err := jsm.IsKnownStream("SHIPPED")
+	checkErr(t, err, "known failed")
+	if known {
+		t.Fatalf("plan created SHIPPED")
+	}
+
+	// without update all existing streams conflict
+	plan, err = jsm.PlanJetStreamConfigurationRestore(dir, false)
+	checkErr(t, err, "plan failed")
+	if len(plan.ItemsWithAction(jsm.RestoreConflict)) != 2 {
+		t.Fatalf("expected 2 conflicts got %+v", plan.Items)
+	}
+}
+
+func TestPlanJetStreamConfigurationRestore_Unchanged(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = jsm.NewStreamTemplate("ARCHIVE", 5, jsm.DefaultStream, jsm.MemoryStorage(), jsm.Subjects("ARCHIVE.*"))
+	checkErr(t, err, "template create failed")
+
+	td, err := ioutil.TempDir("", "")
+	checkErr(t, err, "temp dir failed")
+	defer os.RemoveAll(td)
+
+	dir := filepath.Join(td, "backup")
+	err = jsm.BackupJetStreamConfiguration(dir)
+	checkErr(t, err, "backup failed")
+
+	// an identical stream can not be restored without update
+	plan, err := jsm.PlanJetStreamConfigurationRestore(dir, false)
+	checkErr(t, err, "plan failed")
+
+	actions := map[string]jsm.RestoreAction{}
+	for _, i := range plan.Items {
+		actions[i.Type+":"+i.Name] = i.Action
+	}
+
+	if actions["stream:ORDERS"] != jsm.RestoreConflict || actions["stream_template:ARCHIVE"] != jsm.RestoreConflict {
+		t.Fatalf("expected conflicts got %+v", plan.Items)
+	}
+
+	err = jsm.RestoreJetStreamConfiguration(dir, false)
+	if err == nil {
+		t.Fatalf("expected the restore to fail as planned")
+	}
+
+	// with update the identical stream is unchanged but the existing template still fails the restore
+	plan, err = jsm.PlanJetStreamConfigurationRestore(dir, true)
+	checkErr(t, err, "plan failed")
+
+	actions = map[string]jsm.RestoreAction{}
+	for _, i := range plan.Items {
+		actions[i.Type+":"+i.Name] = i.Action
+	}
+
+	if actions["stream:ORDERS"] != jsm.RestoreUnchanged || actions["stream_template:ARCHIVE"] != jsm.RestoreConflict {
+		t.Fatalf("unexpected plan %+v", plan.Items)
+	}
+
+	err = jsm.RestoreJetStreamConfiguration(dir, true)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected the template restore to fail as planned got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// RetryPolicy configures how idempotent JetStream API requests like INFO, LIST, MSG.BYSEQ and ENABLED are retried,
+// requests that modify JetStream are never retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one, below 2 disables retries
+	MaxAttempts int
+	// InitialBackoff is the time waited before the first retry, it doubles for every following retry
+	InitialBackoff time.Duration
+	// MaxBackoff is the longest time that will be waited between attempts
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the backoff between 0 and 1 that will be randomized
+	Jitter float64
+	// RetryOn determines if a failed attempt should be retried, IsRetryableError is used when not set
+	RetryOn func(error) bool
+}
+
+// DefaultRetryPolicy is a retry policy suitable for riding out short network interruptions and server restarts
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Jitter:         0.2,
+}
+
+// RetryError is returned by requests that failed after being retried
+type RetryError struct {
+	Subject  string
+	Attempts int
+	Err      error
+}
+
+// Error implements error
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("request to %s failed after %d attempts: %s", e.Subject, e.Attempts, e.Err)
+}
+
+// Unwrap supports errors.Is() and errors.As() against the error from the final attempt
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// WithRetryPolicy enables retries of idempotent requests using p
+func WithRetryPolicy(p RetryPolicy) RequestOption {
+	return func(o *reqoptions) {
+		o.retry = &p
+	}
+}
+
+// IsRetryableError determines if err is a transient failure like a timeout or a reconnecting connection, errors returned by the JetStream API are not retryable
+func IsRetryableError(err error) bool {
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+
+	return errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrConnectionReconnecting)
+}
+
+// determines if subj, using the default API prefix, is safe to send more than once
+func isIdempotentRequest(subj string) bool {
+	switch subj {
+	case api.JetStreamEnabled, api.JetStreamInfo, api.JetStreamListStreams, api.JetStreamListTemplates:
+		return true
+	}
+
+	if !strings.HasPrefix(subj, api.JetStreamAPIPrefix+".") {
+		return false
+	}
+
+	for _, suffix := range []string{".INFO", ".CONSUMERS", ".MSG.BYSEQ"} {
+		if strings.HasSuffix(subj, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *RetryPolicy) shouldRetry(err error) bool {
+	if p.RetryOn != nil {
+		return p.RetryOn(err)
+	}
+
+	return IsRetryableError(err)
+}
+
+// backoff is the time to wait after attempt failed
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	jitter := p.Jitter
+	switch {
+	case jitter <= 0 || d <= 0:
+		return d
+	case jitter > 1:
+		jitter = 1
+	}
+
+	spread := float64(d) * jitter
+
+	return time.Duration(float64(d) - spread + rand.Float64()*spread)
+}
+
+func requestWithRetry(subj string, data []byte, opts *reqoptions) (res *nats.Msg, err error) {
+	policy := opts.retry
+	attempt := 0
+
+	for {
+		attempt++
+
+		res, err = requestOnce(subj, data, opts)
+		if err == nil {
+			return res, nil
+		}
+
+		// a done caller supplied context would fail all further attempts
+		if opts.ctx != nil && opts.ctx.Err() != nil {
+			break
+		}
+
+		if attempt >= policy.MaxAttempts || !policy.shouldRetry(err) {
+			break
+		}
+
+		if !sleepContext(opts.ctx, policy.backoff(attempt)) {
+			break
+		}
+	}
+
+	if attempt == 1 {
+		return res, err
+	}
+
+	return res, &RetryError{Subject: subj, Attempts: attempt, Err: err}
+}
+
+// sleeps for d, returns false when ctx is done before d passed
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
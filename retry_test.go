@@ -0,0 +1,128 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+// proxies requests on prefix.$JS.> to $JS.> but ignores the first drop requests
+func startFlakyProxy(t *testing.T, nc *nats.Conn, prefix string, drop int32) *int32 {
+	t.Helper()
+
+	seen := new(int32)
+
+	_, err := nc.Subscribe(prefix+".$JS.>", func(m *nats.Msg) {
+		if atomic.AddInt32(seen, 1) <= drop {
+			return
+		}
+
+		res, err := nc.Request(strings.TrimPrefix(m.Subject, prefix+"."), m.Data, time.Second)
+		if err != nil {
+			return
+		}
+
+		m.Respond(res.Data)
+	})
+	checkErr(t, err, "proxy subscribe failed")
+
+	return seen
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	seen := startFlakyProxy(t, nc, "FLAKY", 2)
+	policy := jsm.RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}
+
+	names, err := jsm.StreamNames(jsm.WithAPIPrefix("FLAKY.$JS"), jsm.WithTimeout(100*time.Millisecond), jsm.WithRetryPolicy(policy))
+	checkErr(t, err, "names failed")
+	if len(names) != 1 || names[0] != "ORDERS" {
+		t.Fatalf("expected [ORDERS] got %v", names)
+	}
+
+	if atomic.LoadInt32(seen) != 3 {
+		t.Fatalf("expected 3 attempts got %d", atomic.LoadInt32(seen))
+	}
+
+	// fails all attempts
+	seen = startFlakyProxy(t, nc, "BROKEN", 100)
+	_, err = jsm.LoadStream("ORDERS", jsm.WithAPIPrefix("BROKEN.$JS"), jsm.WithTimeout(100*time.Millisecond), jsm.WithRetryPolicy(policy))
+	var rerr *jsm.RetryError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a retry error got %v", err)
+	}
+
+	if rerr.Attempts != 3 || atomic.LoadInt32(seen) != 3 {
+		t.Fatalf("expected 3 attempts got %d", rerr.Attempts)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("expected a timeout got %v", rerr.Err)
+	}
+}
+
+func TestWithRetryPolicy_NotIdempotent(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	seen := startFlakyProxy(t, nc, "FLAKY", 1)
+	policy := jsm.RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}
+
+	_, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.StreamConnection(jsm.WithAPIPrefix("FLAKY.$JS"), jsm.WithTimeout(100*time.Millisecond), jsm.WithRetryPolicy(policy)))
+	if err == nil {
+		t.Fatalf("expected create to fail")
+	}
+
+	var rerr *jsm.RetryError
+	if errors.As(err, &rerr) {
+		t.Fatalf("create should not be retried")
+	}
+
+	if atomic.LoadInt32(seen) != 1 {
+		t.Fatalf("expected 1 attempt got %d", atomic.LoadInt32(seen))
+	}
+}
+
+func TestWithRetryPolicy_APIError(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := jsm.LoadStream("MISSING", jsm.WithRetryPolicy(jsm.DefaultRetryPolicy))
+	if !errors.Is(err, api.ErrStreamNotFound) {
+		t.Fatalf("expected stream not found got %v", err)
+	}
+
+	var rerr *jsm.RetryError
+	if errors.As(err, &rerr) {
+		t.Fatalf("api errors should not be retried")
+	}
+}
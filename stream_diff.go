@@ -0,0 +1,150 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// ChangeClass describes the impact of changing a Stream configuration field
+type ChangeClass string
+
+const (
+	// ChangeSafe changes do not affect stored messages
+	ChangeSafe ChangeClass = "safe"
+	// ChangeDestructive changes are allowed but can remove stored messages or stop new ones being stored
+	ChangeDestructive ChangeClass = "destructive"
+	// ChangeForbidden changes are rejected by the server, the Stream has to be recreated
+	ChangeForbidden ChangeClass = "forbidden"
+)
+
+// StreamConfigChange is a classified change to a single Stream configuration field
+type StreamConfigChange struct {
+	FieldChange
+	Class ChangeClass `json:"class"`
+	// Reason explains destructive and forbidden changes
+	Reason string `json:"reason,omitempty"`
+}
+
+// String implements fmt.Stringer
+func (c StreamConfigChange) String() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("%s (%s)", c.FieldChange, c.Class)
+	}
+
+	return fmt.Sprintf("%s (%s: %s)", c.FieldChange, c.Class, c.Reason)
+}
+
+// StreamConfigDiff is the list of changes between two Stream configurations
+type StreamConfigDiff []StreamConfigChange
+
+// DiffStreamConfig compares two Stream configurations field by field and classifies each change by its impact on
+// the Stream, use it before updating a Stream to find changes that will fail or lose data
+func DiffStreamConfig(current api.StreamConfig, desired api.StreamConfig) StreamConfigDiff {
+	diff := StreamConfigDiff{}
+
+	for _, c := range diffConfigs(current, desired) {
+		change := StreamConfigChange{FieldChange: c, Class: ChangeSafe}
+
+		switch {
+		case contains(immutableStreamFields, c.Field):
+			change.Class = ChangeForbidden
+			change.Reason = "can not be changed after creation"
+
+		case c.Field == "max_msgs" && limitLowered(current.MaxMsgs, desired.MaxMsgs):
+			change.Class = ChangeDestructive
+			change.Reason = fmt.Sprintf("the oldest messages beyond the newest %d will be discarded", desired.MaxMsgs)
+
+		case c.Field == "max_bytes" && limitLowered(current.MaxBytes, desired.MaxBytes):
+			change.Class = ChangeDestructive
+			change.Reason = fmt.Sprintf("the oldest messages will be discarded until the Stream holds at most %d bytes", desired.MaxBytes)
+
+		case c.Field == "max_age" && limitLowered(int64(current.MaxAge), int64(desired.MaxAge)):
+			change.Class = ChangeDestructive
+			change.Reason = fmt.Sprintf("messages older than %v will be expired", desired.MaxAge.Round(time.Second))
+
+		case c.Field == "subjects":
+			if removed := removedSubjects(current.Subjects, desired.Subjects); len(removed) > 0 {
+				change.Class = ChangeDestructive
+				change.Reason = fmt.Sprintf("messages published to %s will no longer be stored", strings.Join(removed, ", "))
+			}
+		}
+
+		diff = append(diff, change)
+	}
+
+	return diff
+}
+
+// WithClass is all changes of a specific class
+func (d StreamConfigDiff) WithClass(class ChangeClass) StreamConfigDiff {
+	changes := StreamConfigDiff{}
+	for _, c := range d {
+		if c.Class == class {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes
+}
+
+// Fields are the names of the changed fields
+func (d StreamConfigDiff) Fields() []string {
+	fields := make([]string, len(d))
+	for i, c := range d {
+		fields[i] = c.Field
+	}
+
+	return fields
+}
+
+// FieldChanges are the changes without their classification
+func (d StreamConfigDiff) FieldChanges() []FieldChange {
+	var changes []FieldChange
+	for _, c := range d {
+		changes = append(changes, c.FieldChange)
+	}
+
+	return changes
+}
+
+// IsForbidden determines if any change will be rejected by the server
+func (d StreamConfigDiff) IsForbidden() bool {
+	return len(d.WithClass(ChangeForbidden)) > 0
+}
+
+// IsDestructive determines if any change can remove stored messages
+func (d StreamConfigDiff) IsDestructive() bool {
+	return len(d.WithClass(ChangeDestructive)) > 0
+}
+
+// limitLowered determines if a limit where 0 or less means unlimited became stricter
+func limitLowered(current int64, desired int64) bool {
+	return desired > 0 && (current <= 0 || desired < current)
+}
+
+func removedSubjects(current []string, desired []string) []string {
+	var removed []string
+	for _, s := range current {
+		if !contains(desired, s) {
+			removed = append(removed, s)
+		}
+	}
+
+	return removed
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestDiffStreamConfig(t *testing.T) {
+	current := jsm.DefaultStream
+	current.Name = "ORDERS"
+	current.Subjects = []string{"ORDERS.new", "ORDERS.processed"}
+	current.MaxMsgs = 1000
+	current.MaxAge = time.Hour
+
+	if diff := jsm.DiffStreamConfig(current, current); len(diff) != 0 {
+		t.Fatalf("expected no changes, got %v", diff)
+	}
+
+	desired := current
+	desired.Subjects = []string{"ORDERS.new", "ORDERS.*"}
+	desired.MaxMsgs = 2000
+	desired.MaxBytes = 1024
+	desired.MaxAge = time.Minute
+	desired.Storage = api.MemoryStorage
+	desired.Replicas = 3
+
+	diff := jsm.DiffStreamConfig(current, desired)
+	if !diff.IsForbidden() || !diff.IsDestructive() {
+		t.Fatalf("expected forbidden and destructive changes: %v", diff)
+	}
+
+	if fields := diff.WithClass(jsm.ChangeForbidden).Fields(); !reflect.DeepEqual(fields, []string{"storage"}) {
+		t.Fatalf("unexpected forbidden fields %v", fields)
+	}
+
+	if fields := diff.WithClass(jsm.ChangeDestructive).Fields(); !reflect.DeepEqual(fields, []string{"subjects", "max_bytes", "max_age"}) {
+		t.Fatalf("unexpected destructive fields %v", fields)
+	}
+
+	if fields := diff.WithClass(jsm.ChangeSafe).Fields(); !reflect.DeepEqual(fields, []string{"max_msgs", "num_replicas"}) {
+		t.Fatalf("unexpected safe fields %v", fields)
+	}
+
+	for _, c := range diff.WithClass(jsm.ChangeDestructive) {
+		if c.Field == "subjects" && c.Reason != "messages published to ORDERS.processed will no longer be stored" {
+			t.Fatalf("unexpected reason %q", c.Reason)
+		}
+	}
+
+	lowered := current
+	lowered.MaxMsgs = 10
+	diff = jsm.DiffStreamConfig(current, lowered)
+	if len(diff) != 1 || diff[0].Class != jsm.ChangeDestructive || diff[0].Reason != "the oldest messages beyond the newest 10 will be discarded" {
+		t.Fatalf("unexpected max_msgs change: %v", diff)
+	}
+
+	unlimited := current
+	unlimited.MaxMsgs = -1
+	unlimited.MaxAge = 0
+	if diff := jsm.DiffStreamConfig(current, unlimited); diff.IsDestructive() || len(diff) != 2 {
+		t.Fatalf("removing limits should be safe: %v", diff)
+	}
+
+	if diff := jsm.DiffStreamConfig(unlimited, current); len(diff.WithClass(jsm.ChangeDestructive)) != 2 {
+		t.Fatalf("adding limits should be destructive: %v", diff)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// StreamReaderOption configures a StreamReader
+type StreamReaderOption func(o *streamReaderOptions)
+
+type streamReaderOptions struct {
+	filter    string
+	startSeq  uint64
+	startTime time.Time
+	endSeq    uint64
+	endTime   time.Time
+	idle      time.Duration
+}
+
+// ReaderFilter only reads messages matching subject
+func ReaderFilter(subject string) StreamReaderOption {
+	return func(o *streamReaderOptions) {
+		o.filter = subject
+	}
+}
+
+// ReaderStartSequence starts reading at stream sequence seq
+func ReaderStartSequence(seq uint64) StreamReaderOption {
+	return func(o *streamReaderOptions) {
+		o.startSeq = seq
+		o.startTime = time.Time{}
+	}
+}
+
+// ReaderStartTime starts reading with the first message received at or after t
+func ReaderStartTime(t time.Time) StreamReaderOption {
+	return func(o *streamReaderOptions) {
+		o.startTime = t
+		o.startSeq = 0
+	}
+}
+
+// ReaderEndSequence stops reading after stream sequence seq, by default reading stops at the last message in the
+// Stream when the reader was created
+func ReaderEndSequence(seq uint64) StreamReaderOption {
+	return func(o *streamReaderOptions) {
+		o.endSeq = seq
+	}
+}
+
+// ReaderEndTime stops reading at the first message received after t
+func ReaderEndTime(t time.Time) StreamReaderOption {
+	return func(o *streamReaderOptions) {
+		o.endTime = t
+	}
+}
+
+// ReaderIdleTimeout is how long to wait for a message before checking if the end was reached, messages at the end
+// of the Stream that are deleted or do not match the filter are only noticed this way, defaults to 500ms
+func ReaderIdleTimeout(d time.Duration) StreamReaderOption {
+	return func(o *streamReaderOptions) {
+		if d > 0 {
+			o.idle = d
+		}
+	}
+}
+
+// ReaderMsg is a message read by a StreamReader
+type ReaderMsg struct {
+	api.StoredMsg
+
+	// Gap is the number of stream sequences skipped since the previous message, these are deleted messages or,
+	// when filtering, messages on other subjects
+	Gap uint64
+}
+
+// StreamReader reads messages from a Stream in order using an ephemeral Consumer
+type StreamReader struct {
+	stream string
+	opts   *streamReaderOptions
+
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sub      *nats.Subscription
+	msgs     chan *nats.Msg
+	consumer *Consumer
+
+	end      uint64
+	lastSeq  uint64
+	lastCSeq int
+	done     bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Reader creates a StreamReader that reads the Stream in order, it creates an ephemeral push Consumer that is
+// removed when the reader is closed or ctx is done. The Consumer delivers as fast as the server can send, messages
+// not yet read are buffered in memory so a slow reader holds up to the whole range being read
+func (s *Stream) Reader(ctx context.Context, opts ...StreamReaderOption) (*StreamReader, error) {
+	ropts, err := newreqoptions(s.cfg.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &StreamReader{
+		stream: s.Name(),
+		opts:   &streamReaderOptions{idle: 500 * time.Millisecond},
+	}
+
+	for _, opt := range opts {
+		opt(r.opts)
+	}
+
+	state, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	r.end = state.LastSeq
+	if r.opts.endSeq > 0 && r.opts.endSeq < r.end {
+		r.end = r.opts.endSeq
+	}
+
+	switch {
+	case r.opts.startSeq > 0:
+		r.lastSeq = r.opts.startSeq - 1
+	case r.opts.startTime.IsZero() && state.FirstSeq > 0:
+		r.lastSeq = state.FirstSeq - 1
+	}
+
+	if state.Msgs == 0 || r.opts.startSeq > r.end {
+		r.done = true
+		return r, nil
+	}
+
+	r.parent = ctx
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	// sync subscriptions drop messages once a fixed size buffer is full, an async subscription queues messages
+	// the reader did not get to yet within its pending limits which are removed here
+	r.msgs = make(chan *nats.Msg, 1024)
+	inbox := nats.NewInbox()
+	r.sub, err = ropts.nc.Subscribe(inbox, func(m *nats.Msg) {
+		select {
+		case r.msgs <- m:
+		case <-r.ctx.Done():
+		}
+	})
+	if err != nil {
+		r.cancel()
+		return nil, err
+	}
+
+	err = r.sub.SetPendingLimits(-1, -1)
+	if err != nil {
+		r.sub.Unsubscribe()
+		r.cancel()
+		return nil, err
+	}
+
+	copts := []ConsumerOption{DeliverySubject(inbox), AcknowledgeNone(), ReplayInstantly(), ConsumerConnection(s.cfg.ropts...)}
+	switch {
+	case r.opts.startSeq > 0:
+		copts = append(copts, StartAtSequence(r.opts.startSeq))
+	case !r.opts.startTime.IsZero():
+		copts = append(copts, StartAtTime(r.opts.startTime))
+	default:
+		copts = append(copts, DeliverAllAvailable())
+	}
+
+	if r.opts.filter != "" {
+		copts = append(copts, FilterStreamBySubject(r.opts.filter))
+	}
+
+	r.consumer, err = NewConsumerFromDefault(r.stream, DefaultConsumer, copts...)
+	if err != nil {
+		r.sub.Unsubscribe()
+		r.cancel()
+		return nil, err
+	}
+
+	go func() {
+		<-r.ctx.Done()
+		r.Close()
+	}()
+
+	return r, nil
+}
+
+// Next returns the next message, io.EOF is returned once the end was reached or after the reader was closed
+// and the context error when the context passed to Reader is done
+func (r *StreamReader) Next() (*ReaderMsg, error) {
+	for {
+		if r.done {
+			r.Close()
+			return nil, io.EOF
+		}
+
+		if r.ctx.Err() != nil || r.isClosed() {
+			return nil, r.stopErr()
+		}
+
+		timer := time.NewTimer(r.opts.idle)
+		var m *nats.Msg
+
+		select {
+		case m = <-r.msgs:
+			timer.Stop()
+
+		case <-timer.C:
+			err := r.checkEnd()
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+
+		case <-r.ctx.Done():
+			timer.Stop()
+			return nil, r.stopErr()
+		}
+
+		msg, err := r.handle(m)
+		if err != nil || msg != nil {
+			return msg, err
+		}
+	}
+}
+
+// Close removes the ephemeral Consumer, it's safe to call more than once
+func (r *StreamReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.sub == nil {
+		r.closed = true
+		return nil
+	}
+	r.closed = true
+
+	r.cancel()
+	r.sub.Unsubscribe()
+
+	// ephemeral consumers are removed when their interest is gone, this just speeds that up
+	err := r.consumer.Delete()
+	if errors.Is(err, api.ErrConsumerNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// stopErr is the error for a stopped reader, closing it ends the stream while a done parent context is reported
+func (r *StreamReader) stopErr() error {
+	if r.parent.Err() != nil {
+		return r.parent.Err()
+	}
+
+	return io.EOF
+}
+
+func (r *StreamReader) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.closed
+}
+
+func (r *StreamReader) handle(m *nats.Msg) (*ReaderMsg, error) {
+	info, err := ParseJSMsgMetadata(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// consumer sequences are contiguous, a jump means the subscription dropped messages
+	if r.lastCSeq > 0 && info.ConsumerSequence() != r.lastCSeq+1 {
+		return nil, fmt.Errorf("reader dropped messages after consumer sequence %d, it was too slow", r.lastCSeq)
+	}
+	r.lastCSeq = info.ConsumerSequence()
+
+	seq := uint64(info.StreamSequence())
+	if seq > r.end || (!r.opts.endTime.IsZero() && info.TimeStamp().After(r.opts.endTime)) {
+		r.done = true
+		return nil, nil
+	}
+
+	msg := &ReaderMsg{
+		StoredMsg: api.StoredMsg{
+			Subject:  m.Subject,
+			Sequence: seq,
+			Data:     m.Data,
+			Time:     info.TimeStamp(),
+		},
+	}
+
+	if r.lastSeq > 0 && seq > r.lastSeq+1 {
+		msg.Gap = seq - r.lastSeq - 1
+	}
+	r.lastSeq = seq
+
+	if seq >= r.end {
+		r.done = true
+	}
+
+	return msg, nil
+}
+
+// checkEnd finishes the reader when the consumer moved past the end without delivering messages
+func (r *StreamReader) checkEnd() error {
+	state, err := r.consumer.State()
+	if err != nil {
+		return err
+	}
+
+	if state.Delivered.StreamSeq >= r.end {
+		r.done = true
+	}
+
+	return nil
+}
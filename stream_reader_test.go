@@ -0,0 +1,267 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func readAll(t *testing.T, stream *jsm.Stream, opts ...jsm.StreamReaderOption) []*jsm.ReaderMsg {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reader, err := stream.Reader(ctx, append([]jsm.StreamReaderOption{jsm.ReaderIdleTimeout(100 * time.Millisecond)}, opts...)...)
+	checkErr(t, err, "reader failed")
+	defer reader.Close()
+
+	var msgs []*jsm.ReaderMsg
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			return msgs
+		}
+		checkErr(t, err, "next failed")
+
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestStream_Reader(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	if len(readAll(t, stream)) != 0 {
+		t.Fatalf("expected no messages from an empty stream")
+	}
+
+	var mid time.Time
+	for i := 1; i <= 10; i++ {
+		if i == 6 {
+			time.Sleep(20 * time.Millisecond)
+			mid = time.Now()
+		}
+
+		subj := "ORDERS.new"
+		if i%2 == 0 {
+			subj = "ORDERS.processed"
+		}
+
+		_, err = stream.Publish(subj, []byte(fmt.Sprintf("%d", i)))
+		checkErr(t, err, "publish failed")
+	}
+
+	// the server in use fails to find start times in streams with deleted messages
+	msgs := readAll(t, stream, jsm.ReaderStartTime(mid))
+	if len(msgs) != 5 || msgs[0].Sequence != 6 {
+		t.Fatalf("unexpected messages since %v: %+v", mid, msgs)
+	}
+
+	msgs = readAll(t, stream, jsm.ReaderEndTime(mid))
+	if len(msgs) != 5 || msgs[4].Sequence != 5 {
+		t.Fatalf("unexpected messages until %v: %+v", mid, msgs)
+	}
+
+	checkErr(t, stream.DeleteMessage(3), "delete failed")
+	checkErr(t, stream.DeleteMessage(10), "delete failed")
+
+	msgs = readAll(t, stream)
+	if len(msgs) != 8 {
+		t.Fatalf("expected 8 messages got %d", len(msgs))
+	}
+
+	for i, m := range msgs {
+		if string(m.Data) != fmt.Sprintf("%d", m.Sequence) || m.Time.IsZero() {
+			t.Fatalf("invalid message %+v", m)
+		}
+
+		if i > 0 && m.Sequence <= msgs[i-1].Sequence {
+			t.Fatalf("messages out of order")
+		}
+
+		expectGap := uint64(0)
+		if m.Sequence == 4 {
+			expectGap = 1
+		}
+
+		if m.Gap != expectGap {
+			t.Fatalf("expected gap %d for sequence %d got %d", expectGap, m.Sequence, m.Gap)
+		}
+	}
+
+	msgs = readAll(t, stream, jsm.ReaderFilter("ORDERS.new"))
+	if len(msgs) != 4 || msgs[0].Sequence != 1 || msgs[1].Sequence != 5 || msgs[1].Gap != 3 {
+		t.Fatalf("unexpected filtered messages %+v", msgs)
+	}
+
+	msgs = readAll(t, stream, jsm.ReaderStartSequence(4), jsm.ReaderEndSequence(6))
+	if len(msgs) != 3 || msgs[0].Sequence != 4 || msgs[2].Sequence != 6 {
+		t.Fatalf("unexpected bounded messages %+v", msgs)
+	}
+
+	// the ephemeral consumers are gone
+	names, err := stream.ConsumerNames()
+	checkErr(t, err, "names failed")
+	if len(names) != 0 {
+		t.Fatalf("expected no consumers got %v", names)
+	}
+}
+
+func TestStream_ReaderLargeStream(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	// more than the default subscription pending limit of 65536 messages
+	count := 70000
+	for i := 0; i < count; i++ {
+		checkErr(t, nc.Publish("ORDERS.new", []byte("x")), "publish failed")
+	}
+	checkErr(t, nc.Flush(), "flush failed")
+
+	for i := 0; ; i++ {
+		state, err := stream.State()
+		checkErr(t, err, "state failed")
+		if state.Msgs == uint64(count) {
+			break
+		}
+
+		if i == 100 {
+			t.Fatalf("expected %d messages got %d", count, state.Msgs)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	reader, err := stream.Reader(ctx)
+	checkErr(t, err, "reader failed")
+	defer reader.Close()
+
+	// let the consumer deliver everything before reading so the messages pile up in the subscription
+	for i := 0; ; i++ {
+		names, err := stream.ConsumerNames()
+		checkErr(t, err, "names failed")
+
+		consumer, err := stream.LoadConsumer(names[0])
+		checkErr(t, err, "load failed")
+
+		state, err := consumer.State()
+		checkErr(t, err, "state failed")
+		if state.Delivered.StreamSeq == uint64(count) {
+			break
+		}
+
+		if i == 100 {
+			t.Fatalf("expected %d messages to be delivered got %d", count, state.Delivered.StreamSeq)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	read := 0
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		checkErr(t, err, "next failed")
+
+		read++
+	}
+
+	if read != count {
+		t.Fatalf("expected %d messages got %d", count, read)
+	}
+}
+
+func TestStream_ReaderClose(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 5; i++ {
+		_, err = stream.Publish("ORDERS.new", []byte("1"))
+		checkErr(t, err, "publish failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reader, err := stream.Reader(ctx)
+	checkErr(t, err, "reader failed")
+
+	_, err = reader.Next()
+	checkErr(t, err, "next failed")
+
+	checkErr(t, reader.Close(), "close failed")
+
+	for i := 0; i < 2; i++ {
+		_, err = reader.Next()
+		if err != io.EOF {
+			t.Fatalf("expected io.EOF after close got %v", err)
+		}
+	}
+}
+
+func TestStream_ReaderContext(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.Publish("ORDERS.new", []byte("1"))
+	checkErr(t, err, "publish failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, err := stream.Reader(ctx, jsm.ReaderEndSequence(100))
+	checkErr(t, err, "reader failed")
+
+	cancel()
+
+	_, err = reader.Next()
+	if err == nil || err == io.EOF {
+		t.Fatalf("expected a context error got %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		names, err := stream.ConsumerNames()
+		checkErr(t, err, "names failed")
+		if len(names) == 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("the reader consumer was not removed")
+}
@@ -15,6 +15,7 @@ package jsm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -64,6 +65,9 @@ type StreamConfig struct {
 
 	conn  *reqoptions
 	ropts []RequestOption
+
+	// dupWindow is kept client side as the server in use does not support duplicate windows
+	dupWindow time.Duration
 }
 
 // NewStreamFromDefault creates a new stream based on a supplied template and options
@@ -77,7 +81,7 @@ func NewStreamFromDefault(name string, dflt api.StreamConfig, opts ...StreamOpti
 
 	valid, errs := cfg.Validate()
 	if !valid {
-		return nil, fmt.Errorf("configuration validation failed: %s", strings.Join(errs, ", "))
+		return nil, validationError(errs)
 	}
 
 	jreq, err := json.Marshal(&cfg)
@@ -90,7 +94,14 @@ func NewStreamFromDefault(name string, dflt api.StreamConfig, opts ...StreamOpti
 		return nil, err
 	}
 
-	return LoadStream(name, cfg.ropts...)
+	stream, err = LoadStream(name, cfg.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	stream.cfg.dupWindow = cfg.dupWindow
+
+	return stream, nil
 }
 
 // LoadOrNewStreamFromDefault loads an existing stream or creates a new one matching opts and template
@@ -101,11 +112,16 @@ func LoadOrNewStreamFromDefault(name string, dflt api.StreamConfig, opts ...Stre
 	}
 
 	s, err := LoadStream(name, cfg.ropts...)
-	if s == nil || err != nil {
+	if errors.Is(err, api.ErrStreamNotFound) {
 		return NewStreamFromDefault(name, dflt, opts...)
 	}
+	if err != nil {
+		return nil, err
+	}
 
-	return s, err
+	s.cfg.dupWindow = cfg.dupWindow
+
+	return s, nil
 }
 
 // NewStream creates a new stream using DefaultStream as a starting template allowing adjustments to be made using options
@@ -238,6 +254,16 @@ func MaxAge(m time.Duration) StreamOption {
 	}
 }
 
+// DuplicateWindow sets the window within which messages published with the same ID are considered duplicates by
+// caches made using Stream.NewDedupCache. The server in use does not support duplicate windows, the window is only
+// kept by the Stream returned when creating or loading it with this option
+func DuplicateWindow(d time.Duration) StreamOption {
+	return func(o *StreamConfig) error {
+		o.dupWindow = d
+		return nil
+	}
+}
+
 func MaxMessageSize(m int32) StreamOption {
 	return func(o *StreamConfig) error {
 		o.StreamConfig.MaxMsgSize = m
@@ -285,13 +311,22 @@ func StreamConnection(opts ...RequestOption) StreamOption {
 	}
 }
 
-// UpdateConfiguration updates the stream using cfg modified by opts, reloads configuration from the server post update
+// UpdateConfiguration updates the stream using cfg modified by opts, reloads configuration from the server post update.
+// Changes to fields that can not be updated fail without contacting the server, see DiffStreamConfig
 func (s *Stream) UpdateConfiguration(cfg api.StreamConfig, opts ...StreamOption) error {
 	ncfg, err := NewStreamConfiguration(cfg, opts...)
 	if err != nil {
 		return err
 	}
 
+	forbidden := DiffStreamConfig(s.Configuration(), ncfg.StreamConfig).WithClass(ChangeForbidden)
+	if len(forbidden) > 0 {
+		return &api.APIError{
+			Code:        api.ErrCodeInvalidConfiguration,
+			Description: fmt.Sprintf("%s can not be changed", strings.Join(forbidden.Fields(), ", ")),
+		}
+	}
+
 	jcfg, err := json.Marshal(ncfg)
 	if err != nil {
 		return err
@@ -438,12 +473,12 @@ func (s *Stream) DeleteMessage(seq int) (err error) {
 
 // AdvisorySubject is a wildcard subscription subject that subscribes to all advisories for this stream
 func (s *Stream) AdvisorySubject() string {
-	return api.JetStreamAdvisoryPrefix + "." + "*" + "." + s.Name() + ".*"
+	return s.cfg.conn.apiSubject(api.JetStreamAdvisoryPrefix + "." + "*" + "." + s.Name() + ".*")
 }
 
 // MetricSubject is a wildcard subscription subject that subscribes to all advisories for this stream
 func (s *Stream) MetricSubject() string {
-	return api.JetStreamMetricPrefix + "." + "*" + "." + s.Name() + ".*"
+	return s.cfg.conn.apiSubject(api.JetStreamMetricPrefix + "." + "*" + "." + s.Name() + ".*")
 }
 
 // IsTemplateManaged determines if this stream is managed by a template
@@ -462,3 +497,4 @@ func (s *Stream) Storage() api.StorageType        { return s.cfg.Storage }
 func (s *Stream) Replicas() int                   { return s.cfg.Replicas }
 func (s *Stream) NoAck() bool                     { return s.cfg.NoAck }
 func (s *Stream) Template() string                { return s.cfg.Template }
+func (s *Stream) DuplicateWindow() time.Duration  { return s.cfg.dupWindow }
@@ -14,6 +14,7 @@
 package jsm_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -135,8 +136,8 @@ func TestLoadStream(t *testing.T) {
 	}
 
 	_, err = jsm.LoadStream("q1")
-	if err == nil {
-		t.Fatal("expected error")
+	if !errors.Is(err, api.ErrStreamNotFound) {
+		t.Fatalf("expected stream not found error got %v", err)
 	}
 
 	_, err = jsm.NewStream("q1", jsm.FileStorage())
@@ -269,6 +270,15 @@ func TestStream_UpdateConfiguration(t *testing.T) {
 	if stream.Configuration().Subjects[0] != "ARCHIVE.*" {
 		t.Fatalf("expected [ARCHIVE.*], got %v", stream.Configuration().Subjects)
 	}
+
+	err = stream.UpdateConfiguration(stream.Configuration(), jsm.MemoryStorage())
+	if err == nil || err.Error() != "storage can not be changed" {
+		t.Fatalf("expected storage change to fail, got %v", err)
+	}
+
+	if !errors.Is(err, api.ErrInvalidConfiguration) {
+		t.Fatalf("expected an invalid configuration error, got %#v", err)
+	}
 }
 
 func TestStream_ConsumerNames(t *testing.T) {
@@ -394,8 +404,8 @@ func TestStream_LoadMessage(t *testing.T) {
 	}
 
 	msg, err = stream.LoadMessage(2)
-	if err == nil {
-		t.Fatalf("LoadMessage didnt fail on unknown message")
+	if !errors.Is(err, api.ErrMessageNotFound) {
+		t.Fatalf("LoadMessage didnt fail on unknown message: %v", err)
 	}
 
 }
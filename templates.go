@@ -15,8 +15,8 @@ package jsm
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/nats-io/jsm.go/api"
 )
@@ -48,7 +48,7 @@ func NewStreamTemplate(name string, maxStreams uint32, config api.StreamConfig,
 
 	valid, errs := tc.Validate()
 	if !valid {
-		return nil, fmt.Errorf("configuration validation failed: %s", strings.Join(errs, ", "))
+		return nil, validationError(errs)
 	}
 
 	jreq, err := json.Marshal(&tc)
@@ -66,12 +66,17 @@ func NewStreamTemplate(name string, maxStreams uint32, config api.StreamConfig,
 
 // LoadOrNewStreamTemplate loads an existing template, else creates a new one based on config
 func LoadOrNewStreamTemplate(name string, maxStreams uint32, config api.StreamConfig, opts ...StreamOption) (template *StreamTemplate, err error) {
-	template, err = LoadStreamTemplate(name)
-	if template != nil && err == nil {
-		return template, nil
+	cfg, err := NewStreamConfiguration(config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err = LoadStreamTemplate(name, cfg.ropts...)
+	if errors.Is(err, api.ErrTemplateNotFound) {
+		return NewStreamTemplate(name, maxStreams, config, opts...)
 	}
 
-	return NewStreamTemplate(name, maxStreams, config, opts...)
+	return template, err
 }
 
 // LoadStreamTemplate loads a given stream template from JetStream
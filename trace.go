@@ -0,0 +1,153 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RequestTrace describes a completed JetStream API request
+type RequestTrace struct {
+	// Subject is the subject the request was sent to after any API prefix was applied
+	Subject string
+	// RequestSize is the size of the request payload in bytes
+	RequestSize int
+	// Response is the message received from the server, nil when no response was received
+	Response *nats.Msg
+	// ResponseSize is the size of the response payload in bytes
+	ResponseSize int
+	// Duration is how long the request took including any retries
+	Duration time.Duration
+	// Error is the error returned to the caller
+	Error error
+}
+
+// RequestObserver is called before every JetStream API request, the request is made using the context it returns - or ctx
+// when nil - and the function it returns - if not nil - is called once the request completed
+type RequestObserver func(ctx context.Context, subject string, data []byte) (context.Context, func(RequestTrace))
+
+// WithRequestObserver adds an observer that will be called around every JetStream API request, can be given multiple times
+func WithRequestObserver(o RequestObserver) RequestOption {
+	return func(opts *reqoptions) {
+		if o != nil {
+			opts.observers = append(opts.observers, o)
+		}
+	}
+}
+
+// Tracer starts spans for JetStream API requests, a thin wrapper around an OpenTelemetry tracer satisfies this
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced JetStream API request
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// SpanObserver creates a RequestObserver that records every request as a span started using t
+func SpanObserver(t Tracer) RequestObserver {
+	return func(ctx context.Context, subject string, data []byte) (context.Context, func(RequestTrace)) {
+		ctx, span := t.Start(ctx, "jetstream.request")
+		span.SetAttribute("messaging.system", "nats")
+		span.SetAttribute("messaging.destination", subject)
+		span.SetAttribute("messaging.request.size", len(data))
+
+		return ctx, func(trace RequestTrace) {
+			span.SetAttribute("messaging.response.size", trace.ResponseSize)
+			if trace.Error != nil {
+				span.RecordError(trace.Error)
+			}
+
+			span.End()
+		}
+	}
+}
+
+// StructuredLogger is a logger that accepts loosely typed key value pairs, zap's SugaredLogger satisfies this
+type StructuredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// LoggingObserver creates a RequestObserver that logs every completed request to l, failed requests are logged as errors
+func LoggingObserver(l StructuredLogger) RequestObserver {
+	return func(ctx context.Context, _ string, _ []byte) (context.Context, func(RequestTrace)) {
+		return ctx, func(trace RequestTrace) {
+			kv := []interface{}{
+				"subject", trace.Subject,
+				"request_size", trace.RequestSize,
+				"response_size", trace.ResponseSize,
+				"duration", trace.Duration,
+			}
+
+			if trace.Error != nil {
+				l.Errorw("JetStream API request failed", append(kv, "error", trace.Error)...)
+				return
+			}
+
+			l.Debugw("JetStream API request", kv...)
+		}
+	}
+}
+
+// startTrace notifies all observers of a new request, the request should be made using the returned options that hold
+// the context the observers returned, the returned function completes the trace
+func (o *reqoptions) startTrace(subj string, data []byte) (*reqoptions, func(res *nats.Msg, err error)) {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	subj = o.apiSubject(subj)
+	start := time.Now()
+
+	var done []func(RequestTrace)
+	for _, observer := range o.observers {
+		octx, d := observer(ctx, subj, data)
+		if octx != nil {
+			ctx = octx
+		}
+
+		if d != nil {
+			done = append(done, d)
+		}
+	}
+
+	traced := o.clone()
+	traced.traceCtx = ctx
+
+	return traced, func(res *nats.Msg, err error) {
+		trace := RequestTrace{
+			Subject:     subj,
+			RequestSize: len(data),
+			Response:    res,
+			Duration:    time.Since(start),
+			Error:       err,
+		}
+
+		if res != nil {
+			trace.ResponseSize = len(res.Data)
+		}
+
+		for _, d := range done {
+			d(trace)
+		}
+	}
+}
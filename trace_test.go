@@ -0,0 +1,195 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+type traceRecorder struct {
+	sync.Mutex
+	started []string
+	traces  []jsm.RequestTrace
+}
+
+func (r *traceRecorder) observe(ctx context.Context, subject string, _ []byte) (context.Context, func(jsm.RequestTrace)) {
+	r.Lock()
+	r.started = append(r.started, subject)
+	r.Unlock()
+
+	return ctx, func(trace jsm.RequestTrace) {
+		r.Lock()
+		r.traces = append(r.traces, trace)
+		r.Unlock()
+	}
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(k string, v interface{}) { s.attrs[k] = v }
+func (s *testSpan) RecordError(err error)                { s.err = err }
+func (s *testSpan) End()                                 { s.ended = true }
+
+type spanKey struct{}
+
+type testTracer struct {
+	spans  []*testSpan
+	cancel bool
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, jsm.Span) {
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, s)
+
+	ctx = context.WithValue(ctx, spanKey{}, s)
+	if t.cancel {
+		var cancel func()
+		ctx, cancel = context.WithCancel(ctx)
+		cancel()
+	}
+
+	return ctx, s
+}
+
+type testLogger struct {
+	debug []string
+	error []string
+}
+
+func (l *testLogger) Debugw(msg string, kv ...interface{}) {
+	l.debug = append(l.debug, fmt.Sprintf("%s %v", msg, kv))
+}
+
+func (l *testLogger) Errorw(msg string, kv ...interface{}) {
+	l.error = append(l.error, fmt.Sprintf("%s %v", msg, kv))
+}
+
+func TestWithRequestObserver(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	rec := &traceRecorder{}
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage(), jsm.StreamConnection(jsm.WithRequestObserver(rec.observe)))
+	checkErr(t, err, "create failed")
+
+	// the stream remembers the observer
+	_, err = stream.NewConsumer(jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+
+	_, err = jsm.LoadStream("MISSING", jsm.WithRequestObserver(rec.observe))
+	if !errors.Is(err, api.ErrStreamNotFound) {
+		t.Fatalf("expected stream not found got %v", err)
+	}
+
+	rec.Lock()
+	defer rec.Unlock()
+
+	expected := []string{"$JS.STREAM.ORDERS.CREATE", "$JS.STREAM.ORDERS.INFO", "$JS.STREAM.ORDERS.CONSUMER.NEW.CREATE", "$JS.STREAM.ORDERS.CONSUMER.NEW.INFO", "$JS.STREAM.MISSING.INFO"}
+	if len(rec.started) != len(expected) || len(rec.traces) != len(expected) {
+		t.Fatalf("expected %d traces got %v", len(expected), rec.started)
+	}
+
+	for i, subj := range expected {
+		if rec.started[i] != subj || rec.traces[i].Subject != subj {
+			t.Fatalf("expected request %d to be %s got %s", i, subj, rec.traces[i].Subject)
+		}
+	}
+
+	create := rec.traces[0]
+	if create.RequestSize == 0 || create.Response == nil || create.ResponseSize == 0 || create.Duration == 0 || create.Error != nil {
+		t.Fatalf("invalid create trace: %+v", create)
+	}
+
+	missing := rec.traces[4]
+	if !errors.Is(missing.Error, api.ErrStreamNotFound) {
+		t.Fatalf("expected stream not found got %v", missing.Error)
+	}
+}
+
+func TestSpanObserver(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	tracer := &testTracer{}
+	_, err := jsm.StreamNames(jsm.WithRequestObserver(jsm.SpanObserver(tracer)))
+	checkErr(t, err, "names failed")
+
+	_, err = jsm.LoadStream("MISSING", jsm.WithRequestObserver(jsm.SpanObserver(tracer)))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans got %d", len(tracer.spans))
+	}
+
+	names := tracer.spans[0]
+	if !names.ended || names.err != nil || names.attrs["messaging.destination"] != api.JetStreamListStreams {
+		t.Fatalf("invalid span: %+v", names)
+	}
+
+	if !tracer.spans[1].ended || !errors.Is(tracer.spans[1].err, api.ErrStreamNotFound) {
+		t.Fatalf("expected the error to be recorded: %+v", tracer.spans[1])
+	}
+
+	// later observers and the request itself get the context holding the span
+	var seen interface{}
+	after := func(ctx context.Context, _ string, _ []byte) (context.Context, func(jsm.RequestTrace)) {
+		seen = ctx.Value(spanKey{})
+		return nil, nil
+	}
+
+	tracer.cancel = true
+	_, err = jsm.StreamNames(jsm.WithRequestObserver(jsm.SpanObserver(tracer)), jsm.WithRequestObserver(after))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the request to use the span context got %v", err)
+	}
+
+	if seen != tracer.spans[2] {
+		t.Fatalf("expected the span in the context of later observers")
+	}
+}
+
+func TestLoggingObserver(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	log := &testLogger{}
+	_, err := jsm.StreamNames(jsm.WithRequestObserver(jsm.LoggingObserver(log)))
+	checkErr(t, err, "names failed")
+
+	_, err = jsm.LoadStream("MISSING", jsm.WithRequestObserver(jsm.LoggingObserver(log)))
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if len(log.debug) != 1 || len(log.error) != 1 {
+		t.Fatalf("expected 1 debug and 1 error log got %v and %v", log.debug, log.error)
+	}
+}
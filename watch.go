@@ -0,0 +1,277 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// WatchEventType is the kind of change reported by a Watch
+type WatchEventType string
+
+const (
+	// WatchStreamMessages is a change in the number of messages in the Stream
+	WatchStreamMessages WatchEventType = "stream_messages"
+	// WatchStreamSequenceJump means the Stream sequences moved in a way not explained by new messages, like a purge
+	WatchStreamSequenceJump WatchEventType = "stream_sequence_jump"
+	// WatchStreamConfigChanged is a change in the Stream configuration
+	WatchStreamConfigChanged WatchEventType = "stream_config"
+	// WatchStreamDeleted means the Stream was deleted, no further events will be sent
+	WatchStreamDeleted WatchEventType = "stream_deleted"
+	// WatchConsumerCreated is a new Consumer on the Stream
+	WatchConsumerCreated WatchEventType = "consumer_created"
+	// WatchConsumerDeleted is a Consumer that was removed from the Stream
+	WatchConsumerDeleted WatchEventType = "consumer_deleted"
+	// WatchConsumerConfigChanged is a change in a Consumer configuration, typically because it was recreated
+	WatchConsumerConfigChanged WatchEventType = "consumer_config"
+	// WatchConsumerState is a change in the delivered, acknowledged or pending messages of a Consumer
+	WatchConsumerState WatchEventType = "consumer_state"
+	// WatchError is a failure to poll the state, the Watch continues
+	WatchError WatchEventType = "error"
+)
+
+// WatchEvent is a single change detected by a Watch
+type WatchEvent struct {
+	Type     WatchEventType
+	Stream   string
+	Consumer string
+	Time     time.Time
+	// Delta is the change in number of messages for WatchStreamMessages
+	Delta int64
+	// StreamState is the current Stream state for Stream events
+	StreamState *api.StreamState
+	// ConsumerState is the current Consumer state for Consumer events
+	ConsumerState *api.ConsumerState
+	// Changes are the changed fields for configuration events
+	Changes []FieldChange
+	Error   error
+}
+
+// WatchOption configures a Watch
+type WatchOption func(o *watchOptions)
+
+type watchOptions struct {
+	interval  time.Duration
+	consumers bool
+	ropts     []RequestOption
+}
+
+// WatchInterval sets how often the state is polled, defaults to 5 seconds
+func WatchInterval(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// WatchConsumers sets if Consumers should be watched, defaults to true
+func WatchConsumers(w bool) WatchOption {
+	return func(o *watchOptions) {
+		o.consumers = w
+	}
+}
+
+// WatchConnection sets the connection properties to use while polling
+func WatchConnection(opts ...RequestOption) WatchOption {
+	return func(o *watchOptions) {
+		o.ropts = append(o.ropts, opts...)
+	}
+}
+
+type watchSnapshot struct {
+	stream    *api.StreamInfo
+	consumers map[string]*api.ConsumerInfo
+}
+
+// WatchStream polls a Stream and its Consumers and sends changes to the returned channel, polls that find no changes
+// send nothing. The first poll establishes the starting state. The channel is closed when ctx is done or the Stream
+// was deleted
+func WatchStream(ctx context.Context, stream string, opts ...WatchOption) (<-chan WatchEvent, error) {
+	wopts := &watchOptions{interval: 5 * time.Second, consumers: true}
+	for _, opt := range opts {
+		opt(wopts)
+	}
+
+	ropts, err := newreqoptions(wopts.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := watchPoll(stream, wopts, ropts)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent, 100)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(wopts.interval)
+		defer ticker.Stop()
+
+		send := func(e WatchEvent) bool {
+			e.Stream = stream
+			e.Time = time.Now()
+
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			cur, err := watchPoll(stream, wopts, ropts)
+			if errors.Is(err, api.ErrStreamNotFound) {
+				send(WatchEvent{Type: WatchStreamDeleted})
+				return
+			}
+
+			if err != nil {
+				if !send(WatchEvent{Type: WatchError, Error: err}) {
+					return
+				}
+				continue
+			}
+
+			for _, e := range watchDiff(prev, cur) {
+				if !send(e) {
+					return
+				}
+			}
+
+			prev = cur
+		}
+	}()
+
+	return events, nil
+}
+
+// Watch polls the Stream and its Consumers for changes, see WatchStream
+func (s *Stream) Watch(ctx context.Context, opts ...WatchOption) (<-chan WatchEvent, error) {
+	return WatchStream(ctx, s.Name(), append([]WatchOption{WatchConnection(s.cfg.ropts...)}, opts...)...)
+}
+
+func watchPoll(stream string, wopts *watchOptions, ropts *reqoptions) (*watchSnapshot, error) {
+	info, err := loadStreamInfo(stream, ropts)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &watchSnapshot{stream: info, consumers: make(map[string]*api.ConsumerInfo)}
+	if !wopts.consumers {
+		return snap, nil
+	}
+
+	names, err := ConsumerNames(stream, wopts.ropts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		cinfo, err := loadConsumerInfo(stream, name, ropts)
+		if errors.Is(err, api.ErrConsumerNotFound) {
+			// deleted since listing, the next poll will report it
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		snap.consumers[name] = &cinfo
+	}
+
+	return snap, nil
+}
+
+func watchDiff(prev *watchSnapshot, cur *watchSnapshot) []WatchEvent {
+	var events []WatchEvent
+
+	ps, cs := prev.stream.State, cur.stream.State
+	state := cs
+
+	if changes := diffConfigs(prev.stream.Config, cur.stream.Config); len(changes) > 0 {
+		events = append(events, WatchEvent{Type: WatchStreamConfigChanged, StreamState: &state, Changes: changes})
+	}
+
+	// everything known before is gone or the sequences went backwards, typically a purge or a recreated stream
+	if cs.LastSeq < ps.LastSeq || (cs.FirstSeq > ps.LastSeq && ps.Msgs > 0) {
+		events = append(events, WatchEvent{Type: WatchStreamSequenceJump, StreamState: &state})
+	}
+
+	if cs.Msgs != ps.Msgs {
+		events = append(events, WatchEvent{Type: WatchStreamMessages, StreamState: &state, Delta: int64(cs.Msgs) - int64(ps.Msgs)})
+	}
+
+	for _, name := range sortedConsumerNames(cur.consumers) {
+		c := cur.consumers[name]
+		cstate := c.State
+
+		p, ok := prev.consumers[name]
+		if !ok {
+			events = append(events, WatchEvent{Type: WatchConsumerCreated, Consumer: name, ConsumerState: &cstate})
+			continue
+		}
+
+		if changes := diffConfigs(p.Config, c.Config); len(changes) > 0 {
+			events = append(events, WatchEvent{Type: WatchConsumerConfigChanged, Consumer: name, ConsumerState: &cstate, Changes: changes})
+		}
+
+		if p.State.Delivered != c.State.Delivered || p.State.AckFloor != c.State.AckFloor || !samePending(p.State.Pending, c.State.Pending) {
+			events = append(events, WatchEvent{Type: WatchConsumerState, Consumer: name, ConsumerState: &cstate})
+		}
+	}
+
+	for _, name := range sortedConsumerNames(prev.consumers) {
+		if _, ok := cur.consumers[name]; !ok {
+			events = append(events, WatchEvent{Type: WatchConsumerDeleted, Consumer: name})
+		}
+	}
+
+	return events
+}
+
+func samePending(a map[uint64]int64, b map[uint64]int64) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedConsumerNames(consumers map[string]*api.ConsumerInfo) []string {
+	names := make([]string, 0, len(consumers))
+	for name := range consumers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestStream_Watch(t *testing.T) {
+	srv, nc := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream, err := jsm.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.NewConsumer(jsm.DurableName("OLD"))
+	checkErr(t, err, "consumer create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := stream.Watch(ctx, jsm.WatchInterval(50*time.Millisecond))
+	checkErr(t, err, "watch failed")
+
+	expect := func(etype jsm.WatchEventType) jsm.WatchEvent {
+		t.Helper()
+
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					t.Fatalf("events closed while waiting for %s", etype)
+				}
+
+				if e.Type == etype {
+					return e
+				}
+
+				if e.Type == jsm.WatchError {
+					t.Fatalf("watch failed: %s", e.Error)
+				}
+
+			case <-ctx.Done():
+				t.Fatalf("timeout waiting for %s", etype)
+			}
+		}
+	}
+
+	// unchanged ticks send nothing
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = stream.Publish("ORDERS.new", []byte("x"))
+		checkErr(t, err, "publish failed")
+	}
+
+	e := expect(jsm.WatchStreamMessages)
+	if e.Stream != "ORDERS" || e.Delta < 1 {
+		t.Fatalf("invalid event %+v", e)
+	}
+
+	_, err = stream.NewConsumer(jsm.DurableName("NEW"))
+	checkErr(t, err, "consumer create failed")
+	e = expect(jsm.WatchConsumerCreated)
+	if e.Consumer != "NEW" {
+		t.Fatalf("invalid event %+v", e)
+	}
+
+	old, err := stream.LoadConsumer("OLD")
+	checkErr(t, err, "load failed")
+	checkErr(t, old.Delete(), "delete failed")
+	e = expect(jsm.WatchConsumerDeleted)
+	if e.Consumer != "OLD" {
+		t.Fatalf("invalid event %+v", e)
+	}
+
+	checkErr(t, stream.Purge(), "purge failed")
+	expect(jsm.WatchStreamSequenceJump)
+
+	checkErr(t, stream.UpdateConfiguration(stream.Configuration(), jsm.MaxMessages(10)), "update failed")
+	e = expect(jsm.WatchStreamConfigChanged)
+	if len(e.Changes) != 1 || e.Changes[0].Field != "max_msgs" {
+		t.Fatalf("invalid changes %+v", e.Changes)
+	}
+
+	checkErr(t, stream.Delete(), "delete failed")
+	expect(jsm.WatchStreamDeleted)
+
+	_, ok := <-events
+	if ok {
+		t.Fatalf("expected events to be closed")
+	}
+}